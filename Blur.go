@@ -0,0 +1,206 @@
+package Netpbm
+
+import "math"
+
+// boxPass averages every sample with the samples from left before it to right
+// after it, along the rows or along the columns. It keeps a running sum, so
+// its cost does not depend on the window size. Edge pixels are repeated.
+func boxPass(src channel, left, right int, horizontal bool) channel {
+	width, height := src.size()
+	dst := newChannel(width, height)
+	if width == 0 || height == 0 {
+		return dst
+	}
+	n := float64(left + right + 1)
+
+	if horizontal {
+		parallelRows(height, func(y0, y1 int) {
+			for y := y0; y < y1; y++ {
+				row := src[y]
+				sum := 0.0
+				for k := -left; k <= right; k++ {
+					sum += row[clampIndex(k, width)]
+				}
+				for x := 0; x < width; x++ {
+					dst[y][x] = sum / n
+					sum += row[clampIndex(x+right+1, width)] - row[clampIndex(x-left, width)]
+				}
+			}
+		})
+		return dst
+	}
+
+	parallelRows(height, func(y0, y1 int) {
+		sums := make([]float64, width)
+		for k := y0 - left; k <= y0+right; k++ {
+			row := src[clampIndex(k, height)]
+			for x := range sums {
+				sums[x] += row[x]
+			}
+		}
+		for y := y0; y < y1; y++ {
+			in, out := src[clampIndex(y+right+1, height)], src[clampIndex(y-left, height)]
+			for x := range sums {
+				dst[y][x] = sums[x] / n
+				sums[x] += in[x] - out[x]
+			}
+		}
+	})
+	return dst
+}
+
+// boxBlurChannel averages the channel over a square window of the given radius.
+func boxBlurChannel(c channel, radius int) channel {
+	if radius <= 0 {
+		return c
+	}
+	return boxPass(boxPass(c, radius, radius, true), radius, radius, false)
+}
+
+// gaussianBoxes returns the radii of three box blurs whose succession
+// approximates a Gaussian blur of standard deviation sigma.
+func gaussianBoxes(sigma float64) [3]int {
+	const n = 3
+	ideal := math.Sqrt(12*sigma*sigma/n + 1)
+	lower := int(math.Floor(ideal))
+	if lower%2 == 0 {
+		lower--
+	}
+	upper := lower + 2
+	m := int(math.Round((12*sigma*sigma - n*float64(lower*lower) - 4*n*float64(lower) - 3*n) / (-4*float64(lower) - 4)))
+
+	var radii [3]int
+	for i := range radii {
+		size := upper
+		if i < m {
+			size = lower
+		}
+		radii[i] = max((size-1)/2, 0)
+	}
+	return radii
+}
+
+// gaussianBlurChannel blurs the channel with three successive box blurs,
+// which approximates a Gaussian in a time that does not depend on sigma.
+func gaussianBlurChannel(c channel, sigma float64) channel {
+	if sigma <= 0 {
+		return c
+	}
+	for _, r := range gaussianBoxes(sigma) {
+		c = boxBlurChannel(c, r)
+	}
+	return c
+}
+
+// stackBlurChannel blurs the channel with a triangular kernel of the given
+// radius, the weights growing linearly towards the center like Mario
+// Klingemann's stack blur. The triangle is built from two box passes per axis.
+func stackBlurChannel(c channel, radius int) channel {
+	if radius <= 0 {
+		return c
+	}
+	a := radius / 2
+	b := radius - a
+	c = boxPass(boxPass(c, a, b, true), b, a, true)
+	return boxPass(boxPass(c, a, b, false), b, a, false)
+}
+
+// motionKernel returns the normalized kernel of a straight line of the given
+// length in pixels going through the center at angle degrees.
+func motionKernel(length int, angle float64) [][]float64 {
+	half := float64(length-1) / 2
+	rad := angle * math.Pi / 180
+	dx, dy := math.Cos(rad), math.Sin(rad)
+	radius := int(math.Ceil(half)) + 1
+	size := 2*radius + 1
+	k := make([][]float64, size)
+	for i := range k {
+		k[i] = make([]float64, size)
+	}
+
+	// Splat points along the line with bilinear weights
+	steps := 4 * length
+	sum := 0.0
+	for s := 0; s <= steps; s++ {
+		t := -half + 2*half*float64(s)/float64(steps)
+		x, y := float64(radius)+t*dx, float64(radius)+t*dy
+		x0, y0 := math.Floor(x), math.Floor(y)
+		fx, fy := x-x0, y-y0
+		for _, p := range [4]struct {
+			x, y int
+			w    float64
+		}{
+			{int(x0), int(y0), (1 - fx) * (1 - fy)},
+			{int(x0) + 1, int(y0), fx * (1 - fy)},
+			{int(x0), int(y0) + 1, (1 - fx) * fy},
+			{int(x0) + 1, int(y0) + 1, fx * fy},
+		} {
+			if p.x >= 0 && p.x < size && p.y >= 0 && p.y < size {
+				k[p.y][p.x] += p.w
+				sum += p.w
+			}
+		}
+	}
+	for i := range k {
+		for j := range k[i] {
+			k[i][j] /= sum
+		}
+	}
+	return k
+}
+
+// GaussianBlur blurs the PGM image with a Gaussian of standard deviation
+// sigma. It uses three box blurs, so its speed does not depend on sigma.
+func (pgm *PGM) GaussianBlur(sigma float64) {
+	pgm.applyFilter(func(c channel) channel { return gaussianBlurChannel(c, sigma) })
+}
+
+// GaussianBlur blurs the PPM image with a Gaussian of standard deviation
+// sigma. It uses three box blurs, so its speed does not depend on sigma.
+func (ppm *PPM) GaussianBlur(sigma float64) {
+	ppm.applyFilter(func(c channel) channel { return gaussianBlurChannel(c, sigma) })
+}
+
+// BoxBlur replaces every pixel of the PGM image by the average of the square
+// of the given radius around it.
+func (pgm *PGM) BoxBlur(radius int) {
+	pgm.applyFilter(func(c channel) channel { return boxBlurChannel(c, radius) })
+}
+
+// BoxBlur replaces every pixel of the PPM image by the average of the square
+// of the given radius around it.
+func (ppm *PPM) BoxBlur(radius int) {
+	ppm.applyFilter(func(c channel) channel { return boxBlurChannel(c, radius) })
+}
+
+// StackBlur blurs the PGM image with a triangular kernel of the given radius,
+// which looks close to a Gaussian blur at the cost of a box blur.
+func (pgm *PGM) StackBlur(radius int) {
+	pgm.applyFilter(func(c channel) channel { return stackBlurChannel(c, radius) })
+}
+
+// StackBlur blurs the PPM image with a triangular kernel of the given radius,
+// which looks close to a Gaussian blur at the cost of a box blur.
+func (ppm *PPM) StackBlur(radius int) {
+	ppm.applyFilter(func(c channel) channel { return stackBlurChannel(c, radius) })
+}
+
+// MotionBlur smears the PGM image along a line of the given length in pixels
+// and direction in degrees, 0 being horizontal.
+func (pgm *PGM) MotionBlur(length int, angle float64) {
+	if length <= 1 {
+		return
+	}
+	k := motionKernel(length, angle)
+	pgm.applyFilter(func(c channel) channel { return convolveChannel(c, k, EdgeClamp) })
+}
+
+// MotionBlur smears the PPM image along a line of the given length in pixels
+// and direction in degrees, 0 being horizontal.
+func (ppm *PPM) MotionBlur(length int, angle float64) {
+	if length <= 1 {
+		return
+	}
+	k := motionKernel(length, angle)
+	ppm.applyFilter(func(c channel) channel { return convolveChannel(c, k, EdgeClamp) })
+}
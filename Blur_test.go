@@ -0,0 +1,133 @@
+package Netpbm
+
+import (
+	"math"
+	"testing"
+)
+
+// impulse returns a size x size channel that is 0 everywhere but 1 at its
+// center.
+func impulse(size int) channel {
+	c := newChannel(size, size)
+	c[size/2][size/2] = 1
+	return c
+}
+
+func TestBoxBlur(t *testing.T) {
+	src := randomPGM(17, 9, 256, 1).channel()
+	width, height := src.size()
+	for _, radius := range []int{1, 2, 5, 12} {
+		got := boxBlurChannel(src, radius)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				// Mean of the window, edge pixels repeated
+				sum := 0.0
+				for dy := -radius; dy <= radius; dy++ {
+					for dx := -radius; dx <= radius; dx++ {
+						sum += src[clampIndex(y+dy, height)][clampIndex(x+dx, width)]
+					}
+				}
+				want := sum / float64((2*radius+1)*(2*radius+1))
+				if math.Abs(got[y][x]-want) > 1e-9 {
+					t.Fatalf("radius %d: pixel (%d, %d) is %g, want %g", radius, x, y, got[y][x], want)
+				}
+			}
+		}
+	}
+}
+
+func TestStackBlur(t *testing.T) {
+	// Away from the edges the kernel is a triangle of half width radius+1
+	for _, radius := range []int{1, 2, 3, 6} {
+		size := 4*radius + 5
+		got := stackBlurChannel(impulse(size), radius)
+		total := float64((radius + 1) * (radius + 1))
+		for y := range got {
+			for x := range got[y] {
+				dx, dy := math.Abs(float64(x-size/2)), math.Abs(float64(y-size/2))
+				want := math.Max(float64(radius+1)-dx, 0) / total * math.Max(float64(radius+1)-dy, 0) / total
+				if math.Abs(got[y][x]-want) > 1e-9 {
+					t.Fatalf("radius %d: pixel (%d, %d) is %g, want %g", radius, x, y, got[y][x], want)
+				}
+			}
+		}
+	}
+}
+
+func TestGaussianBlurSpread(t *testing.T) {
+	// The impulse response keeps its mass and has about the requested
+	// variance. Box sizes are odd integers, so smaller sigmas are coarser.
+	for _, sigma := range []float64{2, 3.5, 6, 10} {
+		size := int(12*sigma) + 1
+		got := gaussianBlurChannel(impulse(size), sigma)
+		sum, variance := 0.0, 0.0
+		for y := range got {
+			for x, v := range got[y] {
+				d := float64(x - size/2)
+				sum += v
+				variance += v * d * d
+			}
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("sigma %g: response sums to %g", sigma, sum)
+		}
+		if math.Abs(math.Sqrt(variance)-sigma)/sigma > 0.1 {
+			t.Errorf("sigma %g: response has standard deviation %g", sigma, math.Sqrt(variance))
+		}
+	}
+}
+
+func TestMotionKernel(t *testing.T) {
+	tests := []struct {
+		length int
+		angle  float64
+	}{
+		{3, 0},
+		{5, 90},
+		{8, 45},
+		{9, 30},
+	}
+	for _, tt := range tests {
+		k := motionKernel(tt.length, tt.angle)
+		center := len(k) / 2
+		rad := tt.angle * math.Pi / 180
+		sum := 0.0
+		for y := range k {
+			for x, v := range k[y] {
+				sum += v
+				if v == 0 {
+					continue
+				}
+				// Every weight lies within a pixel of the line
+				dx, dy := float64(x-center), float64(y-center)
+				along := dx*math.Cos(rad) + dy*math.Sin(rad)
+				across := -dx*math.Sin(rad) + dy*math.Cos(rad)
+				if math.Abs(across) >= 1.5 || math.Abs(along) > float64(tt.length)/2+1 {
+					t.Errorf("length %d, angle %g: weight at (%d, %d) is off the line", tt.length, tt.angle, x, y)
+				}
+			}
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("length %d, angle %g: weights sum to %g", tt.length, tt.angle, sum)
+		}
+	}
+}
+
+func TestBlursKeepFlatImages(t *testing.T) {
+	blurs := []struct {
+		name string
+		blur func(pgm *PGM)
+	}{
+		{"box", func(pgm *PGM) { pgm.BoxBlur(3) }},
+		{"gaussian", func(pgm *PGM) { pgm.GaussianBlur(2.5) }},
+		{"stack", func(pgm *PGM) { pgm.StackBlur(4) }},
+		{"motion", func(pgm *PGM) { pgm.MotionBlur(7, 20) }},
+	}
+	for _, b := range blurs {
+		pgm := flatPGM(9, 6, 123, 255)
+		b.blur(pgm)
+		if !samePGM(pgm, flatPGM(9, 6, 123, 255)) {
+			t.Errorf("%s: a flat image changed", b.name)
+		}
+	}
+}
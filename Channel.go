@@ -0,0 +1,184 @@
+package Netpbm
+
+import (
+	"math"
+	"runtime"
+	"sync"
+)
+
+// channel holds a single image plane as floating-point samples so that
+// filters can work without losing precision between passes.
+type channel [][]float64
+
+// newChannel allocates an empty channel of the given size.
+func newChannel(width, height int) channel {
+	c := make(channel, height)
+	for y := range c {
+		c[y] = make([]float64, width)
+	}
+	return c
+}
+
+// size returns the width and height of the channel.
+func (c channel) size() (int, int) {
+	if len(c) == 0 {
+		return 0, 0
+	}
+	return len(c[0]), len(c)
+}
+
+// toUint8 rounds the channel back to samples in the range [0, max].
+func (c channel) toUint8(max uint8) [][]uint8 {
+	width, height := c.size()
+	data := make([][]uint8, height)
+	for y := 0; y < height; y++ {
+		data[y] = make([]uint8, width)
+		for x := 0; x < width; x++ {
+			data[y][x] = clampSample(c[y][x], max)
+		}
+	}
+	return data
+}
+
+// channel returns the gray samples of the PGM image as a channel.
+func (pgm *PGM) channel() channel {
+	c := newChannel(pgm.width, pgm.height)
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			c[y][x] = float64(pgm.data[y][x])
+		}
+	}
+	return c
+}
+
+// channels returns the red, green and blue samples of the PPM image.
+func (ppm *PPM) channels() [3]channel {
+	var ch [3]channel
+	for i := range ch {
+		ch[i] = newChannel(ppm.width, ppm.height)
+	}
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			pixel := ppm.data[y][x]
+			ch[0][y][x] = float64(pixel.R)
+			ch[1][y][x] = float64(pixel.G)
+			ch[2][y][x] = float64(pixel.B)
+		}
+	}
+	return ch
+}
+
+// mergeChannels rounds three channels back into a pixel matrix.
+func mergeChannels(ch [3]channel, max uint8) [][]Pixel {
+	width, height := ch[0].size()
+	data := make([][]Pixel, height)
+	for y := 0; y < height; y++ {
+		data[y] = make([]Pixel, width)
+		for x := 0; x < width; x++ {
+			data[y][x] = Pixel{
+				R: clampSample(ch[0][y][x], max),
+				G: clampSample(ch[1][y][x], max),
+				B: clampSample(ch[2][y][x], max),
+			}
+		}
+	}
+	return data
+}
+
+// clampSample rounds v to the nearest integer in the range [0, max].
+func clampSample(v float64, max uint8) uint8 {
+	if math.IsNaN(v) || v <= 0 {
+		return 0
+	}
+	if v >= float64(max) {
+		return max
+	}
+	return uint8(math.Round(v))
+}
+
+// toPGM converts the PBM image to a gray image where black is 0 and white is 255.
+func (pbm *PBM) toPGM() *PGM {
+	pgm := &PGM{
+		data:        make([][]uint8, pbm.height),
+		width:       pbm.width,
+		height:      pbm.height,
+		magicNumber: "P2",
+		max:         255,
+	}
+	for y := 0; y < pbm.height; y++ {
+		pgm.data[y] = make([]uint8, pbm.width)
+		for x := 0; x < pbm.width; x++ {
+			if !pbm.data[y][x] {
+				pgm.data[y][x] = 255
+			}
+		}
+	}
+	return pgm
+}
+
+// fromPGM replaces the PBM pixels with the gray image, every sample darker
+// than half of the max value becoming black.
+func (pbm *PBM) fromPGM(pgm *PGM) {
+	pbm.width, pbm.height = pgm.width, pgm.height
+	pbm.data = make([][]bool, pgm.height)
+	for y := 0; y < pgm.height; y++ {
+		pbm.data[y] = make([]bool, pgm.width)
+		for x := 0; x < pgm.width; x++ {
+			pbm.data[y][x] = int(pgm.data[y][x])*2 < int(pgm.max)
+		}
+	}
+}
+
+// parallelRows splits the rows [0, height) into bands and calls fn on each
+// band from its own goroutine, returning once every band is done.
+func parallelRows(height int, fn func(y0, y1 int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > height {
+		workers = height
+	}
+	if workers <= 1 {
+		if height > 0 {
+			fn(0, height)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	band := (height + workers - 1) / workers
+	for y0 := 0; y0 < height; y0 += band {
+		y1 := y0 + band
+		if y1 > height {
+			y1 = height
+		}
+		wg.Add(1)
+		go func(y0, y1 int) {
+			defer wg.Done()
+			fn(y0, y1)
+		}(y0, y1)
+	}
+	wg.Wait()
+}
+
+// pixelSamples returns the red, green and blue components of the pixel as floats.
+func pixelSamples(p Pixel) [3]float64 {
+	return [3]float64{float64(p.R), float64(p.G), float64(p.B)}
+}
+
+// luma returns the Rec. 601 luma of the pixel, the weighting used by netpbm.
+func luma(p Pixel) uint8 {
+	return uint8(math.Round(0.299*float64(p.R) + 0.587*float64(p.G) + 0.114*float64(p.B)))
+}
+
+// applyFilter replaces the samples of the PGM image by f applied to them.
+func (pgm *PGM) applyFilter(f func(c channel) channel) {
+	pgm.data = f(pgm.channel()).toUint8(pgm.max)
+}
+
+// applyFilter replaces each channel of the PPM image by f applied to it.
+func (ppm *PPM) applyFilter(f func(c channel) channel) {
+	ch := ppm.channels()
+	for i := range ch {
+		ch[i] = f(ch[i])
+	}
+	ppm.data = mergeChannels(ch, ppm.max)
+}
@@ -0,0 +1,384 @@
+package Netpbm
+
+import (
+	"errors"
+	"math"
+)
+
+// HSV is a color given by hue in degrees [0, 360), and saturation and value in [0, 1].
+type HSV struct {
+	H, S, V float64
+}
+
+// HSL is a color given by hue in degrees [0, 360), and saturation and lightness in [0, 1].
+type HSL struct {
+	H, S, L float64
+}
+
+// YCbCr is a color given by Rec. 601 luma in [0, 1] and chroma differences in [-0.5, 0.5].
+type YCbCr struct {
+	Y, Cb, Cr float64
+}
+
+// XYZ is a CIE 1931 color for the D65 white point, Y being 1 for white.
+type XYZ struct {
+	X, Y, Z float64
+}
+
+// Lab is a CIE L*a*b* color for the D65 white point, L being in [0, 100].
+type Lab struct {
+	L, A, B float64
+}
+
+// D65 reference white
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+// normalized returns the components of the pixel scaled to [0, 1].
+func (p Pixel) normalized(max uint8) (float64, float64, float64) {
+	m := math.Max(float64(max), 1)
+	return float64(p.R) / m, float64(p.G) / m, float64(p.B) / m
+}
+
+// pixelFromNormalized builds a pixel from components in [0, 1].
+func pixelFromNormalized(r, g, b float64, max uint8) Pixel {
+	m := float64(max)
+	return Pixel{clampSample(r*m, max), clampSample(g*m, max), clampSample(b*m, max)}
+}
+
+// hue returns the hue in degrees of a color with the given components, the
+// largest component hi and the spread between the largest and smallest ones.
+func hue(r, g, b, hi, spread float64) float64 {
+	if spread == 0 {
+		return 0
+	}
+	var h float64
+	switch hi {
+	case r:
+		h = math.Mod((g-b)/spread, 6)
+	case g:
+		h = (b-r)/spread + 2
+	default:
+		h = (r-g)/spread + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+// fromHue returns the components of the color of hue h with the given chroma,
+// before the common offset is added.
+func fromHue(h, chroma float64) (float64, float64, float64) {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	h /= 60
+	x := chroma * (1 - math.Abs(math.Mod(h, 2)-1))
+	switch int(h) {
+	case 0:
+		return chroma, x, 0
+	case 1:
+		return x, chroma, 0
+	case 2:
+		return 0, chroma, x
+	case 3:
+		return 0, x, chroma
+	case 4:
+		return x, 0, chroma
+	}
+	return chroma, 0, x
+}
+
+// HSV converts the pixel, whose samples go up to max, to HSV.
+func (p Pixel) HSV(max uint8) HSV {
+	r, g, b := p.normalized(max)
+	hi, lo := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	s := 0.0
+	if hi > 0 {
+		s = (hi - lo) / hi
+	}
+	return HSV{hue(r, g, b, hi, hi-lo), s, hi}
+}
+
+// Pixel converts the color to a pixel whose samples go up to max.
+func (c HSV) Pixel(max uint8) Pixel {
+	chroma := c.V * c.S
+	r, g, b := fromHue(c.H, chroma)
+	m := c.V - chroma
+	return pixelFromNormalized(r+m, g+m, b+m, max)
+}
+
+// HSL converts the pixel, whose samples go up to max, to HSL.
+func (p Pixel) HSL(max uint8) HSL {
+	r, g, b := p.normalized(max)
+	hi, lo := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	l := (hi + lo) / 2
+	s := 0.0
+	if hi != lo {
+		s = (hi - lo) / (1 - math.Abs(2*l-1))
+	}
+	return HSL{hue(r, g, b, hi, hi-lo), s, l}
+}
+
+// Pixel converts the color to a pixel whose samples go up to max.
+func (c HSL) Pixel(max uint8) Pixel {
+	chroma := (1 - math.Abs(2*c.L-1)) * c.S
+	r, g, b := fromHue(c.H, chroma)
+	m := c.L - chroma/2
+	return pixelFromNormalized(r+m, g+m, b+m, max)
+}
+
+// YCbCr converts the pixel, whose samples go up to max, to YCbCr.
+func (p Pixel) YCbCr(max uint8) YCbCr {
+	r, g, b := p.normalized(max)
+	y := 0.299*r + 0.587*g + 0.114*b
+	return YCbCr{y, (b - y) / 1.772, (r - y) / 1.402}
+}
+
+// Pixel converts the color to a pixel whose samples go up to max.
+func (c YCbCr) Pixel(max uint8) Pixel {
+	r := c.Y + 1.402*c.Cr
+	b := c.Y + 1.772*c.Cb
+	g := (c.Y - 0.299*r - 0.114*b) / 0.587
+	return pixelFromNormalized(r, g, b, max)
+}
+
+// XYZ converts the pixel, whose samples go up to max and are sRGB encoded, to CIE XYZ.
+func (p Pixel) XYZ(max uint8) XYZ {
+	r, g, b := p.normalized(max)
+	r, g, b = srgbToLinear(r), srgbToLinear(g), srgbToLinear(b)
+	return XYZ{
+		X: 0.4124564*r + 0.3575761*g + 0.1804375*b,
+		Y: 0.2126729*r + 0.7151522*g + 0.0721750*b,
+		Z: 0.0193339*r + 0.1191920*g + 0.9503041*b,
+	}
+}
+
+// Pixel converts the color to an sRGB pixel whose samples go up to max.
+func (c XYZ) Pixel(max uint8) Pixel {
+	r := 3.2404542*c.X - 1.5371385*c.Y - 0.4985314*c.Z
+	g := -0.9692660*c.X + 1.8760108*c.Y + 0.0415560*c.Z
+	b := 0.0556434*c.X - 0.2040259*c.Y + 1.0572252*c.Z
+	clamp := func(v float64) float64 { return linearToSRGB(math.Min(math.Max(v, 0), 1)) }
+	return pixelFromNormalized(clamp(r), clamp(g), clamp(b), max)
+}
+
+// Lab converts the color to CIE L*a*b*.
+func (c XYZ) Lab() Lab {
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+	fx, fy, fz := f(c.X/whiteX), f(c.Y/whiteY), f(c.Z/whiteZ)
+	return Lab{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+// XYZ converts the color to CIE XYZ.
+func (c Lab) XYZ() XYZ {
+	fy := (c.L + 16) / 116
+	fx := fy + c.A/500
+	fz := fy - c.B/200
+	f := func(t float64) float64 {
+		if t*t*t > 216.0/24389 {
+			return t * t * t
+		}
+		return (116*t - 16) * 27 / 24389
+	}
+	return XYZ{f(fx) * whiteX, f(fy) * whiteY, f(fz) * whiteZ}
+}
+
+// Lab converts the pixel, whose samples go up to max and are sRGB encoded, to CIE L*a*b*.
+func (p Pixel) Lab(max uint8) Lab {
+	return p.XYZ(max).Lab()
+}
+
+// Pixel converts the color to an sRGB pixel whose samples go up to max.
+func (c Lab) Pixel(max uint8) Pixel {
+	return c.XYZ().Pixel(max)
+}
+
+// mapPixels returns the result of f for every pixel of the PPM image.
+func mapPixels[T any](ppm *PPM, f func(p Pixel) T) [][]T {
+	out := make([][]T, ppm.height)
+	for y := 0; y < ppm.height; y++ {
+		out[y] = make([]T, ppm.width)
+		for x := 0; x < ppm.width; x++ {
+			out[y][x] = f(ppm.data[y][x])
+		}
+	}
+	return out
+}
+
+// ppmFrom builds a PPM image of the given max value from a matrix of colors.
+func ppmFrom[T any](data [][]T, max uint8, f func(c T) Pixel) *PPM {
+	ppm := &PPM{data: make([][]Pixel, len(data)), height: len(data), magicNumber: "P3", max: max}
+	if len(data) > 0 {
+		ppm.width = len(data[0])
+	}
+	for y := range data {
+		ppm.data[y] = make([]Pixel, len(data[y]))
+		for x, c := range data[y] {
+			ppm.data[y][x] = f(c)
+		}
+	}
+	return ppm
+}
+
+// ToHSV converts every pixel of the PPM image to HSV.
+func (ppm *PPM) ToHSV() [][]HSV {
+	return mapPixels(ppm, func(p Pixel) HSV { return p.HSV(ppm.max) })
+}
+
+// ToHSL converts every pixel of the PPM image to HSL.
+func (ppm *PPM) ToHSL() [][]HSL {
+	return mapPixels(ppm, func(p Pixel) HSL { return p.HSL(ppm.max) })
+}
+
+// ToYCbCr converts every pixel of the PPM image to YCbCr.
+func (ppm *PPM) ToYCbCr() [][]YCbCr {
+	return mapPixels(ppm, func(p Pixel) YCbCr { return p.YCbCr(ppm.max) })
+}
+
+// ToXYZ converts every pixel of the PPM image to CIE XYZ.
+func (ppm *PPM) ToXYZ() [][]XYZ {
+	return mapPixels(ppm, func(p Pixel) XYZ { return p.XYZ(ppm.max) })
+}
+
+// ToLab converts every pixel of the PPM image to CIE L*a*b*.
+func (ppm *PPM) ToLab() [][]Lab {
+	return mapPixels(ppm, func(p Pixel) Lab { return p.Lab(ppm.max) })
+}
+
+// PPMFromHSV builds a PPM image with the given max value from HSV colors.
+func PPMFromHSV(data [][]HSV, max uint8) *PPM {
+	return ppmFrom(data, max, func(c HSV) Pixel { return c.Pixel(max) })
+}
+
+// PPMFromHSL builds a PPM image with the given max value from HSL colors.
+func PPMFromHSL(data [][]HSL, max uint8) *PPM {
+	return ppmFrom(data, max, func(c HSL) Pixel { return c.Pixel(max) })
+}
+
+// PPMFromYCbCr builds a PPM image with the given max value from YCbCr colors.
+func PPMFromYCbCr(data [][]YCbCr, max uint8) *PPM {
+	return ppmFrom(data, max, func(c YCbCr) Pixel { return c.Pixel(max) })
+}
+
+// PPMFromXYZ builds a PPM image with the given max value from CIE XYZ colors.
+func PPMFromXYZ(data [][]XYZ, max uint8) *PPM {
+	return ppmFrom(data, max, func(c XYZ) Pixel { return c.Pixel(max) })
+}
+
+// PPMFromLab builds a PPM image with the given max value from CIE L*a*b* colors.
+func PPMFromLab(data [][]Lab, max uint8) *PPM {
+	return ppmFrom(data, max, func(c Lab) Pixel { return c.Pixel(max) })
+}
+
+// RotateHue shifts the hue of every pixel of the PPM image by the given angle in degrees.
+func (ppm *PPM) RotateHue(angle float64) {
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			c := ppm.data[y][x].HSV(ppm.max)
+			c.H += angle
+			ppm.data[y][x] = c.Pixel(ppm.max)
+		}
+	}
+}
+
+// AdjustSaturation multiplies the saturation of every pixel of the PPM image
+// by factor, 0 giving gray levels and values above 1 giving stronger colors.
+func (ppm *PPM) AdjustSaturation(factor float64) {
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			c := ppm.data[y][x].HSL(ppm.max)
+			c.S = math.Min(math.Max(c.S*factor, 0), 1)
+			ppm.data[y][x] = c.Pixel(ppm.max)
+		}
+	}
+}
+
+// DeltaE returns the CIEDE2000 color difference between two colors. A
+// difference around 1 is the smallest that most people can notice.
+func DeltaE(c1, c2 Lab) float64 {
+	const kL, kC, kH = 1.0, 1.0, 1.0
+	deg := math.Pi / 180
+
+	cab := (math.Hypot(c1.A, c1.B) + math.Hypot(c2.A, c2.B)) / 2
+	g := 0.5 * (1 - math.Sqrt(math.Pow(cab, 7)/(math.Pow(cab, 7)+math.Pow(25, 7))))
+	a1, a2 := (1+g)*c1.A, (1+g)*c2.A
+	chroma1, chroma2 := math.Hypot(a1, c1.B), math.Hypot(a2, c2.B)
+	hueAngle := func(b, a float64) float64 {
+		if a == 0 && b == 0 {
+			return 0
+		}
+		h := math.Atan2(b, a) / deg
+		if h < 0 {
+			h += 360
+		}
+		return h
+	}
+	h1, h2 := hueAngle(c1.B, a1), hueAngle(c2.B, a2)
+
+	dL := c2.L - c1.L
+	dC := chroma2 - chroma1
+	dh := 0.0
+	if chroma1*chroma2 != 0 {
+		dh = h2 - h1
+		if dh > 180 {
+			dh -= 360
+		} else if dh < -180 {
+			dh += 360
+		}
+	}
+	dH := 2 * math.Sqrt(chroma1*chroma2) * math.Sin(dh/2*deg)
+
+	meanL := (c1.L + c2.L) / 2
+	meanC := (chroma1 + chroma2) / 2
+	meanH := h1 + h2
+	if chroma1*chroma2 != 0 {
+		switch {
+		case math.Abs(h1-h2) <= 180:
+			meanH /= 2
+		case h1+h2 < 360:
+			meanH = (meanH + 360) / 2
+		default:
+			meanH = (meanH - 360) / 2
+		}
+	}
+
+	t := 1 - 0.17*math.Cos((meanH-30)*deg) + 0.24*math.Cos(2*meanH*deg) +
+		0.32*math.Cos((3*meanH+6)*deg) - 0.20*math.Cos((4*meanH-63)*deg)
+	dTheta := 30 * math.Exp(-math.Pow((meanH-275)/25, 2))
+	rc := 2 * math.Sqrt(math.Pow(meanC, 7)/(math.Pow(meanC, 7)+math.Pow(25, 7)))
+	sl := 1 + 0.015*(meanL-50)*(meanL-50)/math.Sqrt(20+(meanL-50)*(meanL-50))
+	sc := 1 + 0.045*meanC
+	sh := 1 + 0.015*meanC*t
+	rt := -math.Sin(2*dTheta*deg) * rc
+
+	l, c, h := dL/(kL*sl), dC/(kC*sc), dH/(kH*sh)
+	return math.Sqrt(l*l + c*c + h*h + rt*c*h)
+}
+
+// DeltaE returns the CIEDE2000 difference between each pixel of the PPM
+// image and the matching pixel of other, which must have the same size.
+func (ppm *PPM) DeltaE(other *PPM) ([][]float64, error) {
+	if ppm.width != other.width || ppm.height != other.height {
+		return nil, errors.New("images must have the same size")
+	}
+	out := make([][]float64, ppm.height)
+	for y := 0; y < ppm.height; y++ {
+		out[y] = make([]float64, ppm.width)
+		for x := 0; x < ppm.width; x++ {
+			out[y][x] = DeltaE(ppm.data[y][x].Lab(ppm.max), other.data[y][x].Lab(other.max))
+		}
+	}
+	return out, nil
+}
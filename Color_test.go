@@ -0,0 +1,34 @@
+package Netpbm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDeltaE(t *testing.T) {
+	// Pairs from Sharma, Wu and Dalal's CIEDE2000 test data, whose
+	// differences are given to four decimals
+	tests := []struct {
+		c1, c2 Lab
+		want   float64
+	}{
+		{Lab{50, 2.6772, -79.7751}, Lab{50, 0, -82.7485}, 2.0425},
+		{Lab{50, 3.1571, -77.2803}, Lab{50, 0, -82.7485}, 2.8615},
+		{Lab{50, 2.8361, -74.0200}, Lab{50, 0, -82.7485}, 3.4412},
+		{Lab{50, 0, 0}, Lab{50, -1, 2}, 2.3669},
+		{Lab{50, -1, 2}, Lab{50, 0, 0}, 2.3669},
+		{Lab{50, 2.4900, -0.0010}, Lab{50, -2.4900, 0.0009}, 7.1792},
+		{Lab{50, 2.4900, -0.0010}, Lab{50, -2.4900, 0.0010}, 7.1792},
+		{Lab{50, 2.4900, -0.0010}, Lab{50, -2.4900, 0.0011}, 7.2195},
+		{Lab{50, 2.4900, -0.0010}, Lab{50, -2.4900, 0.0012}, 7.2195},
+		{Lab{50, 2.5, 0}, Lab{73, 25, -18}, 27.1492},
+		{Lab{60.2574, -34.0099, 36.2677}, Lab{60.4626, -34.1751, 39.4387}, 1.2644},
+		{Lab{2.0776, 0.0795, -1.1350}, Lab{0.9033, -0.0636, -0.5514}, 0.9082},
+		{Lab{50, 10, 10}, Lab{50, 10, 10}, 0},
+	}
+	for _, tt := range tests {
+		if got := DeltaE(tt.c1, tt.c2); math.Abs(got-tt.want) > 5e-5 {
+			t.Errorf("DeltaE(%v, %v) = %.4f, want %.4f", tt.c1, tt.c2, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package Netpbm
+
+import (
+	"math"
+	"sort"
+)
+
+// ColorStop is a color of a Colormap at a position in [0, 1]. Stop colors
+// use samples from 0 to 255 and are rescaled to the max value of the result.
+// Stops may be listed in any order: when the colormap is used, positions are
+// clamped to [0, 1], NaN counting as 0, and the stops are sorted by position,
+// stops at the same position keeping their order.
+type ColorStop struct {
+	Position float64
+	Color    Pixel
+}
+
+// Colormap is a gradient used to render gray levels in color. Colors are
+// interpolated linearly between its stops, taken in order of position.
+type Colormap []ColorStop
+
+// NewColormap returns a gradient through the given colors, spaced evenly
+// from 0 to 1, like the colormap mode of pgmtoppm.
+func NewColormap(colors ...Pixel) Colormap {
+	cmap := make(Colormap, len(colors))
+	for i, c := range colors {
+		position := 0.0
+		if len(colors) > 1 {
+			position = float64(i) / float64(len(colors)-1)
+		}
+		cmap[i] = ColorStop{position, c}
+	}
+	return cmap
+}
+
+// Stops of the built-in colormaps, copied by their accessors so that callers
+// cannot change them.
+var (
+	viridis = NewColormap(
+		Pixel{68, 1, 84}, Pixel{71, 45, 123}, Pixel{59, 82, 139}, Pixel{44, 114, 142}, Pixel{33, 145, 140},
+		Pixel{40, 174, 128}, Pixel{94, 201, 98}, Pixel{173, 220, 48}, Pixel{253, 231, 37},
+	)
+	magma = NewColormap(
+		Pixel{0, 0, 4}, Pixel{28, 16, 68}, Pixel{79, 18, 123}, Pixel{129, 37, 129}, Pixel{181, 54, 122},
+		Pixel{229, 80, 100}, Pixel{251, 135, 97}, Pixel{254, 194, 135}, Pixel{252, 253, 191},
+	)
+	inferno = NewColormap(
+		Pixel{0, 0, 4}, Pixel{31, 12, 72}, Pixel{85, 15, 109}, Pixel{136, 34, 106}, Pixel{186, 54, 85},
+		Pixel{227, 89, 51}, Pixel{249, 140, 10}, Pixel{249, 201, 50}, Pixel{252, 255, 164},
+	)
+	plasma = NewColormap(
+		Pixel{13, 8, 135}, Pixel{76, 2, 161}, Pixel{126, 3, 168}, Pixel{169, 35, 149}, Pixel{204, 71, 120},
+		Pixel{229, 107, 93}, Pixel{248, 148, 65}, Pixel{253, 195, 40}, Pixel{240, 249, 33},
+	)
+	turbo = NewColormap(
+		Pixel{48, 18, 59}, Pixel{73, 62, 175}, Pixel{68, 106, 238}, Pixel{50, 149, 247}, Pixel{38, 189, 225},
+		Pixel{41, 221, 187}, Pixel{64, 243, 146}, Pixel{102, 253, 109}, Pixel{150, 250, 80}, Pixel{198, 235, 59},
+		Pixel{238, 208, 45}, Pixel{255, 171, 36}, Pixel{255, 128, 29}, Pixel{238, 84, 21}, Pixel{201, 45, 12},
+		Pixel{161, 18, 2}, Pixel{122, 4, 3},
+	)
+	grayscale = NewColormap(Pixel{0, 0, 0}, Pixel{255, 255, 255})
+	jet       = Colormap{
+		{0, Pixel{0, 0, 128}},
+		{0.125, Pixel{0, 0, 255}},
+		{0.375, Pixel{0, 255, 255}},
+		{0.625, Pixel{255, 255, 0}},
+		{0.875, Pixel{255, 0, 0}},
+		{1, Pixel{128, 0, 0}},
+	}
+)
+
+// Viridis returns matplotlib's perceptually uniform purple to green to yellow
+// colormap.
+func Viridis() Colormap {
+	return viridis.clone()
+}
+
+// Magma returns matplotlib's perceptually uniform black to light yellow
+// colormap.
+func Magma() Colormap {
+	return magma.clone()
+}
+
+// Inferno returns matplotlib's perceptually uniform black to yellow colormap.
+func Inferno() Colormap {
+	return inferno.clone()
+}
+
+// Plasma returns matplotlib's perceptually uniform blue to magenta to yellow
+// colormap.
+func Plasma() Colormap {
+	return plasma.clone()
+}
+
+// Turbo returns Google's improved rainbow colormap.
+func Turbo() Colormap {
+	return turbo.clone()
+}
+
+// Grayscale returns the black to white colormap.
+func Grayscale() Colormap {
+	return grayscale.clone()
+}
+
+// Jet returns the classic blue to red rainbow colormap.
+func Jet() Colormap {
+	return jet.clone()
+}
+
+// clone returns a copy of the colormap.
+func (cmap Colormap) clone() Colormap {
+	return append(Colormap(nil), cmap...)
+}
+
+// sorted returns the colormap with its positions clamped to [0, 1] and its
+// stops sorted by position, copying it only when needed.
+func (cmap Colormap) sorted() Colormap {
+	ok := true
+	for i, stop := range cmap {
+		if !(stop.Position >= 0 && stop.Position <= 1) || i > 0 && stop.Position < cmap[i-1].Position {
+			ok = false
+			break
+		}
+	}
+	if ok {
+		return cmap
+	}
+	out := cmap.clone()
+	for i := range out {
+		if !(out[i].Position >= 0) {
+			out[i].Position = 0
+		}
+		out[i].Position = math.Min(out[i].Position, 1)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Position < out[j].Position })
+	return out
+}
+
+// At returns the color of the colormap at position t in [0, 1], with samples
+// scaled to max. Positions outside of the stops take the nearest stop color.
+func (cmap Colormap) At(t float64, max uint8) Pixel {
+	if len(cmap) == 0 {
+		return Pixel{}
+	}
+	cmap = cmap.sorted()
+	scale := float64(max) / 255
+	scaled := func(r, g, b float64) Pixel {
+		return Pixel{clampSample(r*scale, max), clampSample(g*scale, max), clampSample(b*scale, max)}
+	}
+
+	if t <= cmap[0].Position {
+		c := pixelSamples(cmap[0].Color)
+		return scaled(c[0], c[1], c[2])
+	}
+	for i := 1; i < len(cmap); i++ {
+		if t <= cmap[i].Position {
+			a, b := cmap[i-1], cmap[i]
+			f := 0.0
+			if span := b.Position - a.Position; span > 0 {
+				f = (t - a.Position) / span
+			}
+			ca, cb := pixelSamples(a.Color), pixelSamples(b.Color)
+			return scaled(ca[0]+(cb[0]-ca[0])*f, ca[1]+(cb[1]-ca[1])*f, ca[2]+(cb[2]-ca[2])*f)
+		}
+	}
+	c := pixelSamples(cmap[len(cmap)-1].Color)
+	return scaled(c[0], c[1], c[2])
+}
+
+// ToPPMWithColormap renders the PGM image in false color, 0 mapping to the
+// start of the colormap and the max value to its end. The result has the
+// same max value as the PGM image.
+func (pgm *PGM) ToPPMWithColormap(cmap Colormap) *PPM {
+	// Look up every possible gray level once
+	cmap = cmap.sorted()
+	table := make([]Pixel, int(pgm.max)+1)
+	for v := range table {
+		table[v] = cmap.At(float64(v)/math.Max(float64(pgm.max), 1), pgm.max)
+	}
+
+	ppm := &PPM{
+		data:        make([][]Pixel, pgm.height),
+		width:       pgm.width,
+		height:      pgm.height,
+		magicNumber: "P3",
+		max:         pgm.max,
+	}
+	for y := 0; y < pgm.height; y++ {
+		ppm.data[y] = make([]Pixel, pgm.width)
+		for x := 0; x < pgm.width; x++ {
+			ppm.data[y][x] = table[min(int(pgm.data[y][x]), len(table)-1)]
+		}
+	}
+	return ppm
+}
@@ -0,0 +1,41 @@
+package Netpbm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestColormapAt(t *testing.T) {
+	black, white, red := Pixel{0, 0, 0}, Pixel{255, 255, 255}, Pixel{255, 0, 0}
+	tests := []struct {
+		name string
+		cmap Colormap
+		t    float64
+		max  uint8
+		want Pixel
+	}{
+		{"start", NewColormap(black, white), 0, 255, black},
+		{"middle", NewColormap(black, white), 0.5, 255, Pixel{128, 128, 128}},
+		{"end", NewColormap(black, white), 1, 255, white},
+		{"scaled to max", NewColormap(black, white), 1, 15, Pixel{15, 15, 15}},
+		{"before the first stop", Colormap{{0.5, red}, {1, white}}, 0.2, 255, red},
+		{"unordered stops", Colormap{{1, white}, {0, black}}, 0.25, 255, Pixel{64, 64, 64}},
+		{"unordered three stops", Colormap{{1, white}, {0, black}, {0.5, red}}, 0.75, 255, Pixel{255, 128, 128}},
+		{"positions past the range", Colormap{{2, white}, {-1, black}}, 0.5, 255, Pixel{128, 128, 128}},
+		{"NaN position", Colormap{{1, white}, {math.NaN(), black}}, 0, 255, black},
+		{"equal positions keep their order", Colormap{{0, black}, {0.5, red}, {0.5, white}, {1, black}}, 0.5, 255, red},
+	}
+	for _, tt := range tests {
+		if got := tt.cmap.At(tt.t, tt.max); got != tt.want {
+			t.Errorf("%s: At(%g) = %v, want %v", tt.name, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestColormapAccessorsCopy(t *testing.T) {
+	cmap := Viridis()
+	cmap[0].Color = Pixel{1, 2, 3}
+	if Viridis()[0].Color == cmap[0].Color {
+		t.Error("changing the result of Viridis changed the built-in colormap")
+	}
+}
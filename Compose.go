@@ -0,0 +1,297 @@
+package Netpbm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Image is implemented by *PBM, *PGM and *PPM.
+type Image interface {
+	Size() (int, int)
+	Save(filename string) error
+}
+
+// Direction selects whether images are placed side by side or on top of each other.
+type Direction int
+
+const (
+	// Horizontal places images from left to right.
+	Horizontal Direction = iota
+	// Vertical places images from top to bottom.
+	Vertical
+)
+
+// Alignment selects how images smaller than the result are positioned across it.
+type Alignment int
+
+const (
+	// AlignStart aligns images on the top or left edge.
+	AlignStart Alignment = iota
+	// AlignCenter centers images.
+	AlignCenter
+	// AlignEnd aligns images on the bottom or right edge.
+	AlignEnd
+)
+
+// offset returns where an item of the given size starts inside space.
+func (a Alignment) offset(space, size int) int {
+	switch a {
+	case AlignCenter:
+		return (space - size) / 2
+	case AlignEnd:
+		return space - size
+	}
+	return 0
+}
+
+// Image formats in promotion order, each one able to hold the previous one.
+const (
+	formatPBM = iota
+	formatPGM
+	formatPPM
+)
+
+// commonFormat returns the richest format among the images and the largest
+// max value, so that every image can be promoted to it without losing detail.
+func commonFormat(images []Image) (int, uint8, error) {
+	format, maxValue := formatPBM, uint8(1)
+	for i, img := range images {
+		switch img := img.(type) {
+		case *PBM:
+		case *PGM:
+			format = max(format, formatPGM)
+			maxValue = max(maxValue, img.max)
+		case *PPM:
+			format = formatPPM
+			maxValue = max(maxValue, img.max)
+		default:
+			return 0, 0, fmt.Errorf("unsupported image type %T at index %d", img, i)
+		}
+	}
+	return format, maxValue, nil
+}
+
+// rescale converts the sample v from the range [0, from] to [0, to].
+func rescale(v, from, to uint8) uint8 {
+	if from == to {
+		return v
+	}
+	if from == 0 {
+		return 0
+	}
+	return uint8(math.Round(float64(v) * float64(to) / float64(from)))
+}
+
+// toPixels converts the image to a pixel matrix with samples in [0, max].
+func toPixels(img Image, max uint8) [][]Pixel {
+	width, height := img.Size()
+	data := make([][]Pixel, height)
+	for y := 0; y < height; y++ {
+		data[y] = make([]Pixel, width)
+		for x := 0; x < width; x++ {
+			switch img := img.(type) {
+			case *PBM:
+				if !img.data[y][x] {
+					data[y][x] = Pixel{max, max, max}
+				}
+			case *PGM:
+				v := rescale(img.data[y][x], img.max, max)
+				data[y][x] = Pixel{v, v, v}
+			case *PPM:
+				p := img.data[y][x]
+				data[y][x] = Pixel{rescale(p.R, img.max, max), rescale(p.G, img.max, max), rescale(p.B, img.max, max)}
+			}
+		}
+	}
+	return data
+}
+
+// fromPixels builds an image of the given format from a pixel matrix.
+func fromPixels(data [][]Pixel, format int, max uint8) Image {
+	height := len(data)
+	width := 0
+	if height > 0 {
+		width = len(data[0])
+	}
+
+	switch format {
+	case formatPBM:
+		pbm := &PBM{data: make([][]bool, height), width: width, height: height, magicNumber: "P1"}
+		for y := range data {
+			pbm.data[y] = make([]bool, width)
+			for x, p := range data[y] {
+				pbm.data[y][x] = int(luma(p))*2 < int(max)
+			}
+		}
+		return pbm
+	case formatPGM:
+		pgm := &PGM{data: make([][]uint8, height), width: width, height: height, magicNumber: "P2", max: max}
+		for y := range data {
+			pgm.data[y] = make([]uint8, width)
+			for x, p := range data[y] {
+				pgm.data[y][x] = luma(p)
+			}
+		}
+		return pgm
+	}
+	return &PPM{data: data, width: width, height: height, magicNumber: "P3", max: max}
+}
+
+// newCanvas returns a width x height pixel matrix filled with color.
+func newCanvas(width, height int, color Pixel) [][]Pixel {
+	data := make([][]Pixel, height)
+	for y := range data {
+		data[y] = make([]Pixel, width)
+		for x := range data[y] {
+			data[y][x] = color
+		}
+	}
+	return data
+}
+
+// blit copies src onto dst with its top left corner at (x0, y0), clipping at the edges of dst.
+func blit(dst, src [][]Pixel, x0, y0 int) {
+	for y, row := range src {
+		if y0+y < 0 || y0+y >= len(dst) {
+			continue
+		}
+		for x, p := range row {
+			if x0+x >= 0 && x0+x < len(dst[y0+y]) {
+				dst[y0+y][x0+x] = p
+			}
+		}
+	}
+}
+
+// Concat joins the images side by side or on top of each other, like pnmcat.
+// Images are promoted to the richest format among them, and smaller images
+// are positioned with align. The gap between images and the area around
+// smaller images are filled with fill, given in the result's sample range.
+func Concat(images []Image, direction Direction, align Alignment, gap int, fill Pixel) (Image, error) {
+	if len(images) == 0 {
+		return nil, errors.New("no images to concatenate")
+	}
+	format, maxValue, err := commonFormat(images)
+	if err != nil {
+		return nil, err
+	}
+	gap = max(gap, 0)
+
+	// The result is as long as all images and gaps, and as wide as the widest image
+	length, across := gap*(len(images)-1), 0
+	for _, img := range images {
+		width, height := img.Size()
+		if direction == Vertical {
+			width, height = height, width
+		}
+		length += width
+		across = max(across, height)
+	}
+
+	var canvas [][]Pixel
+	if direction == Vertical {
+		canvas = newCanvas(across, length, fill)
+	} else {
+		canvas = newCanvas(length, across, fill)
+	}
+
+	pos := 0
+	for _, img := range images {
+		width, height := img.Size()
+		if direction == Vertical {
+			blit(canvas, toPixels(img, maxValue), align.offset(across, width), pos)
+			pos += height + gap
+		} else {
+			blit(canvas, toPixels(img, maxValue), pos, align.offset(across, height))
+			pos += width + gap
+		}
+	}
+	return fromPixels(canvas, format, maxValue), nil
+}
+
+// Tile repeats the image to fill a width x height canvas, like pnmtile.
+// The result has the same format as the image.
+func Tile(img Image, width, height int) (Image, error) {
+	format, max, err := commonFormat([]Image{img})
+	if err != nil {
+		return nil, err
+	}
+	srcWidth, srcHeight := img.Size()
+	if srcWidth == 0 || srcHeight == 0 || width <= 0 || height <= 0 {
+		return nil, errors.New("tile and canvas must not be empty")
+	}
+
+	src := toPixels(img, max)
+	canvas := newCanvas(width, height, Pixel{})
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			canvas[y][x] = src[y%srcHeight][x%srcWidth]
+		}
+	}
+	return fromPixels(canvas, format, max), nil
+}
+
+// Montage arranges the images on a grid with the given number of columns,
+// like pnmindex. A columns value of 0 or less picks a roughly square grid.
+// Each cell is separated by spacing pixels of background, and when labels is
+// not nil each image gets the matching label written under it.
+// Images are promoted to the richest format among them.
+func Montage(images []Image, columns, spacing int, labels []string, background Pixel) (Image, error) {
+	if len(images) == 0 {
+		return nil, errors.New("no images for the montage")
+	}
+	if labels != nil && len(labels) != len(images) {
+		return nil, fmt.Errorf("got %d labels for %d images", len(labels), len(images))
+	}
+	format, maxValue, err := commonFormat(images)
+	if err != nil {
+		return nil, err
+	}
+	if columns <= 0 {
+		columns = int(math.Ceil(math.Sqrt(float64(len(images)))))
+	}
+	columns = min(columns, len(images))
+	rows := (len(images) + columns - 1) / columns
+	spacing = max(spacing, 0)
+
+	// Every cell is large enough for the largest image and its label
+	cellWidth, cellHeight := 0, 0
+	for i, img := range images {
+		width, height := img.Size()
+		cellWidth = max(cellWidth, width)
+		cellHeight = max(cellHeight, height)
+		if labels != nil {
+			cellWidth = max(cellWidth, textWidth(labels[i]))
+		}
+	}
+	labelTop := cellHeight + 2
+	if labels != nil {
+		cellHeight = labelTop + glyphHeight
+	}
+
+	canvas := &PPM{
+		data:        newCanvas(columns*cellWidth+(columns+1)*spacing, rows*cellHeight+(rows+1)*spacing, background),
+		width:       columns*cellWidth + (columns+1)*spacing,
+		height:      rows*cellHeight + (rows+1)*spacing,
+		magicNumber: "P3",
+		max:         maxValue,
+	}
+
+	// Write labels in black on light backgrounds and in white on dark ones
+	ink := Pixel{}
+	if int(luma(background))*2 < int(maxValue) {
+		ink = Pixel{maxValue, maxValue, maxValue}
+	}
+
+	for i, img := range images {
+		x0 := spacing + (i%columns)*(cellWidth+spacing)
+		y0 := spacing + (i/columns)*(cellHeight+spacing)
+		width, _ := img.Size()
+		blit(canvas.data, toPixels(img, maxValue), x0+(cellWidth-width)/2, y0)
+		if labels != nil {
+			canvas.DrawText(Point{x0 + (cellWidth-textWidth(labels[i]))/2, y0 + labelTop}, labels[i], ink)
+		}
+	}
+	return fromPixels(canvas.data, format, maxValue), nil
+}
@@ -0,0 +1,247 @@
+package Netpbm
+
+import (
+	"errors"
+	"math"
+)
+
+// Kernel is a convolution matrix, like the ones used by pnmconvol. The center
+// of Values, at (len(row)/2, len(Values)/2), lies over the pixel being
+// computed, and the kernel is applied as is, without being flipped.
+type Kernel struct {
+	// Values holds the weights, row by row. It must not be empty and every row
+	// must have the same length, or Convolve returns an error.
+	Values [][]float64
+	// Normalize divides the weights by their sum, when it is not zero, so that
+	// flat areas keep their brightness.
+	Normalize bool
+	// Bias is added to every result, in sample units.
+	Bias float64
+}
+
+// weights returns the kernel values, normalized if requested, or an error
+// when they do not form a rectangle.
+func (k Kernel) weights() ([][]float64, error) {
+	if len(k.Values) == 0 || len(k.Values[0]) == 0 {
+		return nil, errors.New("kernel must not be empty")
+	}
+	for _, row := range k.Values {
+		if len(row) != len(k.Values[0]) {
+			return nil, errors.New("kernel rows must all have the same length")
+		}
+	}
+
+	sum := 0.0
+	for _, row := range k.Values {
+		for _, v := range row {
+			sum += v
+		}
+	}
+	scale := 1.0
+	if k.Normalize && sum != 0 {
+		scale = 1 / sum
+	}
+	w := make([][]float64, len(k.Values))
+	for i, row := range k.Values {
+		w[i] = make([]float64, len(row))
+		for j, v := range row {
+			w[i][j] = v * scale
+		}
+	}
+	return w, nil
+}
+
+// separate splits the weights into a column and a row vector whose outer
+// product gives the weights back, if such vectors exist.
+func separate(w [][]float64) ([]float64, []float64, bool) {
+	if len(w) == 0 || len(w[0]) == 0 {
+		return nil, nil, false
+	}
+	// Use the largest weight as pivot to keep the division stable
+	pi, pj, largest := 0, 0, 0.0
+	for i, row := range w {
+		for j, v := range row {
+			if math.Abs(v) > largest {
+				pi, pj, largest = i, j, math.Abs(v)
+			}
+		}
+	}
+	if largest == 0 {
+		return nil, nil, false
+	}
+
+	column := make([]float64, len(w))
+	for i := range w {
+		column[i] = w[i][pj]
+	}
+	row := make([]float64, len(w[pi]))
+	for j := range row {
+		row[j] = w[pi][j] / w[pi][pj]
+	}
+	for i := range w {
+		for j := range w[i] {
+			if math.Abs(w[i][j]-column[i]*row[j]) > 1e-9*largest {
+				return nil, nil, false
+			}
+		}
+	}
+	return column, row, true
+}
+
+// sourceIndexes returns, for every coordinate in [0, n), the coordinate read
+// at the given offset, or -1 when the constant edge applies.
+func sourceIndexes(n, offset int, edge EdgeMode) []int {
+	indexes := make([]int, n)
+	for i := range indexes {
+		j, ok := edgeIndex(i+offset, n, edge)
+		if !ok {
+			j = -1
+		}
+		indexes[i] = j
+	}
+	return indexes
+}
+
+// convolveChannel applies the weights to the channel, reading pixels outside
+// of it according to the edge mode, EdgeConstant reading zeros. Results are
+// not clamped so that callers can use signed responses.
+func convolveChannel(src channel, w [][]float64, edge EdgeMode) channel {
+	if column, row, ok := separate(w); ok {
+		return convolveColumn(convolveRow(src, row, edge), column, edge)
+	}
+
+	width, height := src.size()
+	dst := newChannel(width, height)
+	if len(w) == 0 {
+		return dst
+	}
+	cy, cx := len(w)/2, len(w[0])/2
+	xs := make([][]int, len(w[0]))
+	for j := range xs {
+		xs[j] = sourceIndexes(width, j-cx, edge)
+	}
+	ys := make([][]int, len(w))
+	for i := range ys {
+		ys[i] = sourceIndexes(height, i-cy, edge)
+	}
+
+	parallelRows(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for i, wrow := range w {
+				sy := ys[i][y]
+				if sy < 0 {
+					continue
+				}
+				srow := src[sy]
+				for j, v := range wrow {
+					if v == 0 {
+						continue
+					}
+					for x, sx := range xs[j] {
+						if sx >= 0 {
+							dst[y][x] += srow[sx] * v
+						}
+					}
+				}
+			}
+		}
+	})
+	return dst
+}
+
+// convolveRow applies the one-dimensional weights along every row.
+func convolveRow(src channel, w []float64, edge EdgeMode) channel {
+	width, height := src.size()
+	dst := newChannel(width, height)
+	c := len(w) / 2
+	xs := make([][]int, len(w))
+	for j := range xs {
+		xs[j] = sourceIndexes(width, j-c, edge)
+	}
+	parallelRows(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for j, v := range w {
+				if v == 0 {
+					continue
+				}
+				for x, sx := range xs[j] {
+					if sx >= 0 {
+						dst[y][x] += src[y][sx] * v
+					}
+				}
+			}
+		}
+	})
+	return dst
+}
+
+// convolveColumn applies the one-dimensional weights along every column.
+func convolveColumn(src channel, w []float64, edge EdgeMode) channel {
+	width, height := src.size()
+	dst := newChannel(width, height)
+	c := len(w) / 2
+	ys := make([][]int, len(w))
+	for i := range ys {
+		ys[i] = sourceIndexes(height, i-c, edge)
+	}
+	parallelRows(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for i, v := range w {
+				sy := ys[i][y]
+				if v == 0 || sy < 0 {
+					continue
+				}
+				for x := 0; x < width; x++ {
+					dst[y][x] += src[sy][x] * v
+				}
+			}
+		}
+	})
+	return dst
+}
+
+// addBias adds the bias to every sample of the channel.
+func (c channel) addBias(bias float64) channel {
+	if bias != 0 {
+		for y := range c {
+			for x := range c[y] {
+				c[y][x] += bias
+			}
+		}
+	}
+	return c
+}
+
+// Convolve applies the kernel to the PGM image, like pnmconvol. Pixels
+// outside of the image are read according to the edge mode, EdgeConstant
+// reading zeros, and results are clamped to [0, max]. Separable kernels are
+// applied as two one-dimensional passes, which is much faster for large kernels.
+// It returns an error, leaving the image unchanged, when the kernel is empty
+// or its rows differ in length.
+func (pgm *PGM) Convolve(k Kernel, edge EdgeMode) error {
+	w, err := k.weights()
+	if err != nil {
+		return err
+	}
+	pgm.applyFilter(func(c channel) channel {
+		return convolveChannel(c, w, edge).addBias(k.Bias)
+	})
+	return nil
+}
+
+// Convolve applies the kernel to each channel of the PPM image, like
+// pnmconvol. Pixels outside of the image are read according to the edge
+// mode, EdgeConstant reading zeros, and results are clamped to [0, max].
+// Separable kernels are applied as two one-dimensional passes. It returns an
+// error, leaving the image unchanged, when the kernel is empty or its rows
+// differ in length.
+func (ppm *PPM) Convolve(k Kernel, edge EdgeMode) error {
+	w, err := k.weights()
+	if err != nil {
+		return err
+	}
+	ppm.applyFilter(func(c channel) channel {
+		return convolveChannel(c, w, edge).addBias(k.Bias)
+	})
+	return nil
+}
@@ -0,0 +1,122 @@
+package Netpbm
+
+import (
+	"math"
+	"testing"
+)
+
+// naiveConvolve applies the weights to the channel pixel by pixel, reading
+// zeros for EdgeConstant.
+func naiveConvolve(src channel, w [][]float64, edge EdgeMode) channel {
+	width, height := src.size()
+	dst := newChannel(width, height)
+	cy, cx := len(w)/2, len(w[0])/2
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			for i := range w {
+				for j := range w[i] {
+					sy, okY := edgeIndex(y+i-cy, height, edge)
+					sx, okX := edgeIndex(x+j-cx, width, edge)
+					if okX && okY {
+						dst[y][x] += src[sy][sx] * w[i][j]
+					}
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// outer returns the weights column[i] * row[j].
+func outer(column, row []float64) [][]float64 {
+	w := make([][]float64, len(column))
+	for i := range w {
+		w[i] = make([]float64, len(row))
+		for j := range row {
+			w[i][j] = column[i] * row[j]
+		}
+	}
+	return w
+}
+
+func TestConvolveSeparable(t *testing.T) {
+	tests := []struct {
+		name      string
+		w         [][]float64
+		separable bool
+	}{
+		{"box", outer([]float64{1, 1, 1}, []float64{1, 1, 1}), true},
+		{"sobel", outer([]float64{1, 2, 1}, []float64{-1, 0, 1}), true},
+		{"wide", outer([]float64{0.5, 2}, []float64{1, -3, 0, 2, 1}), true},
+		{"zero pivot row", outer([]float64{0, 1, 3}, []float64{2, 0, -1}), true},
+		{"single row", [][]float64{{1, 2, 3, 4}}, true},
+		{"sharpen", [][]float64{{0, -1, 0}, {-1, 5, -1}, {0, -1, 0}}, false},
+		{"laplacian", [][]float64{{0, 1, 0}, {1, -4, 1}, {0, 1, 0}}, false},
+		{"diagonal", [][]float64{{1, 0}, {0, 1}}, false},
+	}
+	for _, tt := range tests {
+		if _, _, ok := separate(tt.w); ok != tt.separable {
+			t.Errorf("%s: separable is %v, want %v", tt.name, ok, tt.separable)
+		}
+		for _, edge := range []EdgeMode{EdgeConstant, EdgeClamp, EdgeWrap, EdgeReflect} {
+			src := randomPGM(11, 7, 256, int64(edge)).channel()
+			got := convolveChannel(src, tt.w, edge)
+			want := naiveConvolve(src, tt.w, edge)
+			for y := range want {
+				for x := range want[y] {
+					if math.Abs(got[y][x]-want[y][x]) > 1e-9 {
+						t.Fatalf("%s, edge %d: pixel (%d, %d) is %g, want %g", tt.name, edge, x, y, got[y][x], want[y][x])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestConvolve(t *testing.T) {
+	tests := []struct {
+		name string
+		k    Kernel
+		want []uint8
+	}{
+		{"identity", Kernel{Values: [][]float64{{0, 1, 0}}}, []uint8{10, 20, 30, 40}},
+		{"even width", Kernel{Values: [][]float64{{1, 1, 1, 1}}}, []uint8{30, 60, 100, 90}},
+		{"normalize", Kernel{Values: [][]float64{{1, 2, 1}}, Normalize: true}, []uint8{10, 20, 30, 28}},
+		{"bias", Kernel{Values: [][]float64{{1}}, Bias: 5}, []uint8{15, 25, 35, 45}},
+		{"clamped", Kernel{Values: [][]float64{{-1, 0, 1}}}, []uint8{20, 20, 20, 0}},
+	}
+	for _, tt := range tests {
+		pgm := &PGM{data: [][]uint8{{10, 20, 30, 40}}, width: 4, height: 1, magicNumber: "P2", max: 255}
+		if err := pgm.Convolve(tt.k, EdgeConstant); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		for x, want := range tt.want {
+			if pgm.data[0][x] != want {
+				t.Errorf("%s: got %v, want %v", tt.name, pgm.data[0], tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestConvolveBadKernels(t *testing.T) {
+	tests := []struct {
+		name   string
+		values [][]float64
+	}{
+		{"nil", nil},
+		{"empty row", [][]float64{{}}},
+		{"ragged", [][]float64{{1, 2, 3}, {1, 2}}},
+		{"ragged after an empty row", [][]float64{{}, {1}}},
+	}
+	for _, tt := range tests {
+		pgm := randomPGM(4, 4, 256, 1)
+		if err := pgm.Convolve(Kernel{Values: tt.values}, EdgeClamp); err == nil {
+			t.Errorf("%s: got no error", tt.name)
+		}
+		if !samePGM(pgm, randomPGM(4, 4, 256, 1)) {
+			t.Errorf("%s: image changed on error", tt.name)
+		}
+	}
+}
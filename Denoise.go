@@ -0,0 +1,160 @@
+package Netpbm
+
+import "math"
+
+// bilateral smooths the channels together, weighting every neighbour by its
+// distance to the center pixel and by how far its samples are from the
+// center ones, so that averaging stops at edges. Neighbours outside of the
+// image are ignored.
+func bilateral(src []channel, sigmaSpatial, sigmaRange float64) []channel {
+	if sigmaSpatial <= 0 || sigmaRange <= 0 || len(src) == 0 {
+		return src
+	}
+	width, height := src[0].size()
+	radius := int(math.Ceil(2 * sigmaSpatial))
+	spatial := make([][]float64, 2*radius+1)
+	for dy := -radius; dy <= radius; dy++ {
+		spatial[dy+radius] = make([]float64, 2*radius+1)
+		for dx := -radius; dx <= radius; dx++ {
+			spatial[dy+radius][dx+radius] = math.Exp(-float64(dx*dx+dy*dy) / (2 * sigmaSpatial * sigmaSpatial))
+		}
+	}
+	rangeScale := -1 / (2 * sigmaRange * sigmaRange)
+
+	dst := make([]channel, len(src))
+	for i := range dst {
+		dst[i] = newChannel(width, height)
+	}
+	parallelRows(height, func(y0, y1 int) {
+		sums := make([]float64, len(src))
+		for y := y0; y < y1; y++ {
+			for x := 0; x < width; x++ {
+				for i := range sums {
+					sums[i] = 0
+				}
+				total := 0.0
+				for sy := max(y-radius, 0); sy <= min(y+radius, height-1); sy++ {
+					for sx := max(x-radius, 0); sx <= min(x+radius, width-1); sx++ {
+						d := 0.0
+						for _, c := range src {
+							diff := c[sy][sx] - c[y][x]
+							d += diff * diff
+						}
+						w := spatial[sy-y+radius][sx-x+radius] * math.Exp(d*rangeScale)
+						for i, c := range src {
+							sums[i] += w * c[sy][sx]
+						}
+						total += w
+					}
+				}
+				for i := range dst {
+					dst[i][y][x] = sums[i] / total
+				}
+			}
+		}
+	})
+	return dst
+}
+
+// nonLocalMeans replaces every pixel by the average of the pixels within
+// searchRadius, each weighted by how much the patch of patchRadius around it
+// looks like the patch around the pixel. Patches are compared over all the
+// channels, and pixels outside of the image repeat the edge ones.
+func nonLocalMeans(src []channel, strength float64, patchRadius, searchRadius int) []channel {
+	if strength <= 0 || searchRadius <= 0 || len(src) == 0 {
+		return src
+	}
+	patchRadius = max(patchRadius, 0)
+	width, height := src[0].size()
+	patchSize := float64((2*patchRadius + 1) * (2*patchRadius + 1) * len(src))
+	scale := -1 / (strength * strength)
+
+	// distance returns the mean squared difference between the patches
+	// centered on (x1, y1) and (x2, y2)
+	distance := func(x1, y1, x2, y2 int) float64 {
+		d := 0.0
+		for dy := -patchRadius; dy <= patchRadius; dy++ {
+			r1, r2 := clampIndex(y1+dy, height), clampIndex(y2+dy, height)
+			for dx := -patchRadius; dx <= patchRadius; dx++ {
+				c1, c2 := clampIndex(x1+dx, width), clampIndex(x2+dx, width)
+				for _, c := range src {
+					diff := c[r1][c1] - c[r2][c2]
+					d += diff * diff
+				}
+			}
+		}
+		return d / patchSize
+	}
+
+	dst := make([]channel, len(src))
+	for i := range dst {
+		dst[i] = newChannel(width, height)
+	}
+	parallelRows(height, func(y0, y1 int) {
+		sums := make([]float64, len(src))
+		for y := y0; y < y1; y++ {
+			for x := 0; x < width; x++ {
+				for i := range sums {
+					sums[i] = 0
+				}
+				total, largest := 0.0, 0.0
+				for sy := max(y-searchRadius, 0); sy <= min(y+searchRadius, height-1); sy++ {
+					for sx := max(x-searchRadius, 0); sx <= min(x+searchRadius, width-1); sx++ {
+						if sx == x && sy == y {
+							continue
+						}
+						w := math.Exp(distance(x, y, sx, sy) * scale)
+						for i, c := range src {
+							sums[i] += w * c[sy][sx]
+						}
+						total += w
+						largest = math.Max(largest, w)
+					}
+				}
+				// The pixel itself would always weigh 1, so give it the
+				// weight of its best match instead
+				if largest == 0 {
+					largest = 1
+				}
+				for i, c := range src {
+					dst[i][y][x] = (sums[i] + largest*c[y][x]) / (total + largest)
+				}
+			}
+		}
+	})
+	return dst
+}
+
+// BilateralFilter smooths the PGM image while keeping its edges. Neighbours
+// are weighted by a Gaussian of standard deviation sigmaSpatial on their
+// distance in pixels and sigmaRange on their difference in sample units.
+func (pgm *PGM) BilateralFilter(sigmaSpatial, sigmaRange float64) {
+	pgm.data = bilateral([]channel{pgm.channel()}, sigmaSpatial, sigmaRange)[0].toUint8(pgm.max)
+}
+
+// BilateralFilter smooths the PPM image while keeping its edges, like
+// PGM.BilateralFilter. Color differences are measured over the three
+// channels together, so that edges between colors of the same brightness
+// are kept too.
+func (ppm *PPM) BilateralFilter(sigmaSpatial, sigmaRange float64) {
+	ch := ppm.channels()
+	out := bilateral(ch[:], sigmaSpatial, sigmaRange)
+	ppm.data = mergeChannels([3]channel{out[0], out[1], out[2]}, ppm.max)
+}
+
+// NonLocalMeans denoises the PGM image by averaging every pixel with the
+// pixels within searchRadius whose surrounding patch of patchRadius looks
+// alike. Strength, in sample units, sets how different patches may be and
+// should be close to the noise level. Typical radii are 1 to 3 for patches
+// and 5 to 10 for the search; the cost grows with the square of both.
+func (pgm *PGM) NonLocalMeans(strength float64, patchRadius, searchRadius int) {
+	pgm.data = nonLocalMeans([]channel{pgm.channel()}, strength, patchRadius, searchRadius)[0].toUint8(pgm.max)
+}
+
+// NonLocalMeans denoises the PPM image like PGM.NonLocalMeans, comparing
+// patches over the three channels together.
+func (ppm *PPM) NonLocalMeans(strength float64, patchRadius, searchRadius int) {
+	ch := ppm.channels()
+	out := nonLocalMeans(ch[:], strength, patchRadius, searchRadius)
+	ppm.data = mergeChannels([3]channel{out[0], out[1], out[2]}, ppm.max)
+}
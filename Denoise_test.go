@@ -0,0 +1,132 @@
+package Netpbm
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// stepPGM returns a PGM image whose left half is dark and right half bright.
+func stepPGM(width, height int) *PGM {
+	pgm := flatPGM(width, height, 20, 255)
+	for y := range pgm.data {
+		for x := width / 2; x < width; x++ {
+			pgm.data[y][x] = 220
+		}
+	}
+	return pgm
+}
+
+// stddev returns the standard deviation of the samples of the PGM image.
+func stddev(pgm *PGM) float64 {
+	var sum, squares float64
+	for y := range pgm.data {
+		for _, v := range pgm.data[y] {
+			sum += float64(v)
+			squares += float64(v) * float64(v)
+		}
+	}
+	n := float64(pgm.width * pgm.height)
+	return math.Sqrt(squares/n - sum*sum/n/n)
+}
+
+func TestBilateral(t *testing.T) {
+	sigmaSpatial, sigmaRange := 1.5, 40.0
+	radius := 3
+	ppm := randomPPM(10, 7, 1)
+	ch := ppm.channels()
+	for _, src := range [][]channel{ch[:1], ch[:]} {
+		got := bilateral(src, sigmaSpatial, sigmaRange)
+		width, height := src[0].size()
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				// Weighted mean of the neighbours inside the image, the
+				// color distance taken over all the channels
+				sums := make([]float64, len(src))
+				total := 0.0
+				for sy := 0; sy < height; sy++ {
+					for sx := 0; sx < width; sx++ {
+						if abs(sx-x) > radius || abs(sy-y) > radius {
+							continue
+						}
+						d := 0.0
+						for _, c := range src {
+							d += (c[sy][sx] - c[y][x]) * (c[sy][sx] - c[y][x])
+						}
+						s := float64((sx-x)*(sx-x) + (sy-y)*(sy-y))
+						w := math.Exp(-s/(2*sigmaSpatial*sigmaSpatial) - d/(2*sigmaRange*sigmaRange))
+						for i, c := range src {
+							sums[i] += w * c[sy][sx]
+						}
+						total += w
+					}
+				}
+				for i := range src {
+					if want := sums[i] / total; math.Abs(got[i][y][x]-want) > 1e-9 {
+						t.Fatalf("%d channels: sample %d of pixel (%d, %d) is %g, want %g", len(src), i, x, y, got[i][y][x], want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestDenoiseKeepsFlatImagesAndEdges(t *testing.T) {
+	filters := []struct {
+		name   string
+		filter func(pgm *PGM)
+	}{
+		{"bilateral", func(pgm *PGM) { pgm.BilateralFilter(2, 10) }},
+		{"non-local means", func(pgm *PGM) { pgm.NonLocalMeans(10, 1, 3) }},
+	}
+	for _, f := range filters {
+		for _, want := range []*PGM{flatPGM(9, 7, 77, 255), stepPGM(10, 6)} {
+			got := copyPGM(want)
+			f.filter(got)
+			if !samePGM(got, want) {
+				t.Errorf("%s: image changed to %v", f.name, got.data)
+			}
+		}
+	}
+}
+
+func TestDenoiseReducesNoise(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	noisy := flatPGM(24, 24, 128, 255)
+	for y := range noisy.data {
+		for x := range noisy.data[y] {
+			noisy.data[y][x] = uint8(128 + rng.Intn(31) - 15)
+		}
+	}
+	before := stddev(noisy)
+	filters := []struct {
+		name   string
+		filter func(pgm *PGM)
+	}{
+		{"bilateral", func(pgm *PGM) { pgm.BilateralFilter(2, 30) }},
+		{"non-local means", func(pgm *PGM) { pgm.NonLocalMeans(20, 1, 5) }},
+	}
+	for _, f := range filters {
+		pgm := copyPGM(noisy)
+		f.filter(pgm)
+		if after := stddev(pgm); after > before/2 {
+			t.Errorf("%s: standard deviation went from %.2f to %.2f", f.name, before, after)
+		}
+	}
+}
+
+func TestDenoiseZeroParameters(t *testing.T) {
+	src := randomPGM(6, 5, 256, 1)
+	for _, filter := range []func(pgm *PGM){
+		func(pgm *PGM) { pgm.BilateralFilter(0, 10) },
+		func(pgm *PGM) { pgm.BilateralFilter(2, 0) },
+		func(pgm *PGM) { pgm.NonLocalMeans(0, 1, 3) },
+		func(pgm *PGM) { pgm.NonLocalMeans(10, 1, 0) },
+	} {
+		got := copyPGM(src)
+		filter(got)
+		if !samePGM(got, src) {
+			t.Error("a zero parameter changed the image")
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package Netpbm
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// DitherMethod selects how gray levels are turned into black and white pixels.
+type DitherMethod int
+
+const (
+	// DitherThreshold makes every pixel darker than half of the max value black.
+	DitherThreshold DitherMethod = iota
+	// DitherFloydSteinberg diffuses the error to four neighbours.
+	DitherFloydSteinberg
+	// DitherJarvisJudiceNinke diffuses the error to twelve neighbours over two rows.
+	DitherJarvisJudiceNinke
+	// DitherStucki diffuses the error like Jarvis-Judice-Ninke with other weights.
+	DitherStucki
+	// DitherAtkinson diffuses three quarters of the error, which keeps more contrast.
+	DitherAtkinson
+	// DitherBayer compares pixels against an ordered Bayer matrix.
+	DitherBayer
+	// DitherClusteredDot compares pixels against a matrix that grows round
+	// dots, like a printed halftone.
+	DitherClusteredDot
+	// DitherBlueNoise compares pixels against a blue noise matrix, which has
+	// no visible pattern.
+	DitherBlueNoise
+)
+
+// DitherOptions configures ToPBMDither.
+type DitherOptions struct {
+	// Method is the dithering algorithm.
+	Method DitherMethod
+	// Serpentine scans every other row from right to left for the error
+	// diffusion methods, which reduces directional artifacts.
+	Serpentine bool
+	// MatrixSize is the size of the Bayer matrix, a power of two from 2 to 16,
+	// or of the clustered-dot cell. It defaults to 4 for Bayer and 8 for clustered dots.
+	MatrixSize int
+}
+
+// diffusionWeight spreads part of the error to the pixel at (dx, dy).
+type diffusionWeight struct {
+	dx, dy int
+	weight float64
+}
+
+// diffusionKernels holds the error diffusion weights of each method.
+var diffusionKernels = map[DitherMethod][]diffusionWeight{
+	DitherFloydSteinberg: {
+		{1, 0, 7.0 / 16}, {-1, 1, 3.0 / 16}, {0, 1, 5.0 / 16}, {1, 1, 1.0 / 16},
+	},
+	DitherJarvisJudiceNinke: {
+		{1, 0, 7.0 / 48}, {2, 0, 5.0 / 48},
+		{-2, 1, 3.0 / 48}, {-1, 1, 5.0 / 48}, {0, 1, 7.0 / 48}, {1, 1, 5.0 / 48}, {2, 1, 3.0 / 48},
+		{-2, 2, 1.0 / 48}, {-1, 2, 3.0 / 48}, {0, 2, 5.0 / 48}, {1, 2, 3.0 / 48}, {2, 2, 1.0 / 48},
+	},
+	DitherStucki: {
+		{1, 0, 8.0 / 42}, {2, 0, 4.0 / 42},
+		{-2, 1, 2.0 / 42}, {-1, 1, 4.0 / 42}, {0, 1, 8.0 / 42}, {1, 1, 4.0 / 42}, {2, 1, 2.0 / 42},
+		{-2, 2, 1.0 / 42}, {-1, 2, 2.0 / 42}, {0, 2, 4.0 / 42}, {1, 2, 2.0 / 42}, {2, 2, 1.0 / 42},
+	},
+	DitherAtkinson: {
+		{1, 0, 1.0 / 8}, {2, 0, 1.0 / 8},
+		{-1, 1, 1.0 / 8}, {0, 1, 1.0 / 8}, {1, 1, 1.0 / 8},
+		{0, 2, 1.0 / 8},
+	},
+}
+
+// ToPBMDither converts the PGM image to PBM with the given dithering options.
+// Dark pixels become black, like PPM.ToPBM; note that PGM.ToPBM keeps its
+// historical opposite polarity, bright pixels becoming black.
+func (pgm *PGM) ToPBMDither(opts DitherOptions) *PBM {
+	pbm := &PBM{
+		data:        make([][]bool, pgm.height),
+		width:       pgm.width,
+		height:      pgm.height,
+		magicNumber: "P1",
+	}
+	for y := range pbm.data {
+		pbm.data[y] = make([]bool, pgm.width)
+	}
+
+	// Work on values in [0, 1] so every method shares the same thresholds
+	scale := 1 / math.Max(float64(pgm.max), 1)
+	value := func(x, y int) float64 {
+		return float64(pgm.data[y][x]) * scale
+	}
+
+	if kernel, ok := diffusionKernels[opts.Method]; ok {
+		diffuse(pbm, pgm.channel(), scale, kernel, opts.Serpentine)
+		return pbm
+	}
+
+	var matrix [][]float64
+	switch opts.Method {
+	case DitherBayer:
+		matrix = bayerMatrix(opts.MatrixSize)
+	case DitherClusteredDot:
+		matrix = clusteredDotMatrix(opts.MatrixSize)
+	case DitherBlueNoise:
+		matrix = blueNoiseMatrix()
+	default:
+		matrix = [][]float64{{0.5}}
+	}
+	n := len(matrix)
+	parallelRows(pgm.height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < pgm.width; x++ {
+				pbm.data[y][x] = value(x, y) < matrix[y%n][x%n]
+			}
+		}
+	})
+	return pbm
+}
+
+// ToPBMDither converts the PPM image to PBM with the given dithering options,
+// working on its luminance. Dark pixels become black, and DitherThreshold
+// gives the same bitmap as ToPBM.
+func (ppm *PPM) ToPBMDither(opts DitherOptions) *PBM {
+	return ppm.ToPGM().ToPBMDither(opts)
+}
+
+// diffuse thresholds the samples at the middle of the range and spreads the
+// error of every pixel to its unvisited neighbours.
+func diffuse(pbm *PBM, c channel, scale float64, kernel []diffusionWeight, serpentine bool) {
+	width, height := c.size()
+	for y := 0; y < height; y++ {
+		for x := range c[y] {
+			c[y][x] *= scale
+		}
+	}
+
+	for y := 0; y < height; y++ {
+		// Odd rows go from right to left when scanning in serpentine order
+		reverse := serpentine && y%2 == 1
+		for i := 0; i < width; i++ {
+			x, dir := i, 1
+			if reverse {
+				x, dir = width-1-i, -1
+			}
+			old := c[y][x]
+			out := 0.0
+			if old >= 0.5 {
+				out = 1
+			}
+			pbm.data[y][x] = out == 0
+			err := old - out
+			for _, k := range kernel {
+				nx, ny := x+k.dx*dir, y+k.dy
+				if nx >= 0 && nx < width && ny < height {
+					c[ny][nx] += err * k.weight
+				}
+			}
+		}
+	}
+}
+
+// thresholdMatrix turns a matrix of ranks 0..n²-1 into thresholds in (0, 1).
+func thresholdMatrix(ranks [][]int) [][]float64 {
+	n := len(ranks)
+	m := make([][]float64, n)
+	for y := range ranks {
+		m[y] = make([]float64, n)
+		for x, r := range ranks[y] {
+			m[y][x] = (float64(r) + 0.5) / float64(n*n)
+		}
+	}
+	return m
+}
+
+// bayerMatrix returns the ordered dithering thresholds of the Bayer matrix
+// of the given size, rounded down to a power of two between 2 and 16.
+func bayerMatrix(size int) [][]float64 {
+	if size <= 0 {
+		size = 4
+	}
+	size = min(max(size, 2), 16)
+
+	// Each step doubles the matrix: [[4M, 4M+2], [4M+3, 4M+1]]
+	ranks := [][]int{{0}}
+	for n := 1; n*2 <= size; n *= 2 {
+		next := make([][]int, n*2)
+		for y := range next {
+			next[y] = make([]int, n*2)
+			for x := range next[y] {
+				base := 4 * ranks[y%n][x%n]
+				switch {
+				case y < n && x < n:
+					next[y][x] = base
+				case y < n:
+					next[y][x] = base + 2
+				case x < n:
+					next[y][x] = base + 3
+				default:
+					next[y][x] = base + 1
+				}
+			}
+		}
+		ranks = next
+	}
+	return thresholdMatrix(ranks)
+}
+
+// clusteredDotMatrix returns the thresholds of a halftone cell of the given
+// size, where black grows as a dot from the center of the cell.
+func clusteredDotMatrix(size int) [][]float64 {
+	if size <= 0 {
+		size = 8
+	}
+	size = max(size, 2)
+
+	// Rank the cells by distance to the center: the farthest get the lowest
+	// thresholds and turn black last, so the dot grows from the center
+	type cell struct {
+		x, y int
+		d    float64
+	}
+	cells := make([]cell, 0, size*size)
+	c := float64(size-1) / 2
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := float64(x)-c, float64(y)-c
+			cells = append(cells, cell{x, y, dx*dx + dy*dy})
+		}
+	}
+	sort.SliceStable(cells, func(i, j int) bool { return cells[i].d > cells[j].d })
+
+	ranks := make([][]int, size)
+	for y := range ranks {
+		ranks[y] = make([]int, size)
+	}
+	for r, cl := range cells {
+		ranks[cl.y][cl.x] = r
+	}
+	return thresholdMatrix(ranks)
+}
+
+const blueNoiseSize = 64
+
+var (
+	blueNoiseOnce       sync.Once
+	blueNoiseThresholds [][]float64
+)
+
+// blueNoiseMatrix returns the thresholds of a 64x64 blue noise matrix. It is
+// generated once with the void-and-cluster method from a fixed seed, so
+// results are the same on every run.
+func blueNoiseMatrix() [][]float64 {
+	blueNoiseOnce.Do(func() {
+		blueNoiseThresholds = thresholdMatrix(voidAndCluster(blueNoiseSize, 1.5, 1))
+	})
+	return blueNoiseThresholds
+}
+
+// voidAndCluster ranks the cells of an n x n toroidal grid with Ulichney's
+// void-and-cluster method.
+func voidAndCluster(n int, sigma float64, seed int64) [][]int {
+	size := n * n
+
+	// Gaussian weight for every toroidal offset
+	weights := make([]float64, size)
+	for dy := 0; dy < n; dy++ {
+		for dx := 0; dx < n; dx++ {
+			wx, wy := float64(min(dx, n-dx)), float64(min(dy, n-dy))
+			weights[dy*n+dx] = math.Exp(-(wx*wx + wy*wy) / (2 * sigma * sigma))
+		}
+	}
+
+	pattern := make([]bool, size)
+	energy := make([]float64, size)
+	toggle := func(i int, on bool) {
+		pattern[i] = on
+		sign := 1.0
+		if !on {
+			sign = -1
+		}
+		x0, y0 := i%n, i/n
+		for y := 0; y < n; y++ {
+			row := weights[((y-y0+n)%n)*n:]
+			for x := 0; x < n; x++ {
+				dx := x - x0
+				if dx < 0 {
+					dx += n
+				}
+				energy[y*n+x] += sign * row[dx]
+			}
+		}
+	}
+	// tightest returns the set cell with the highest energy, largest the free
+	// cell with the lowest one.
+	tightest := func() int {
+		best := -1
+		for i := 0; i < size; i++ {
+			if pattern[i] && (best < 0 || energy[i] > energy[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	largest := func() int {
+		best := -1
+		for i := 0; i < size; i++ {
+			if !pattern[i] && (best < 0 || energy[i] < energy[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+
+	// Start from random points and move them until they are evenly spread
+	rng := rand.New(rand.NewSource(seed))
+	ones := size / 10
+	for _, i := range rng.Perm(size)[:ones] {
+		toggle(i, true)
+	}
+	for {
+		cluster := tightest()
+		toggle(cluster, false)
+		void := largest()
+		toggle(void, true)
+		if void == cluster {
+			break
+		}
+	}
+	initial := make([]bool, size)
+	copy(initial, pattern)
+	initialEnergy := make([]float64, size)
+	copy(initialEnergy, energy)
+
+	ranks := make([]int, size)
+	// Rank the initial points by removing the tightest clusters first
+	for r := ones - 1; r >= 0; r-- {
+		i := tightest()
+		toggle(i, false)
+		ranks[i] = r
+	}
+	// Rank the remaining cells by filling the largest voids
+	copy(pattern, initial)
+	copy(energy, initialEnergy)
+	for r := ones; r < size; r++ {
+		i := largest()
+		toggle(i, true)
+		ranks[i] = r
+	}
+
+	matrix := make([][]int, n)
+	for y := range matrix {
+		matrix[y] = ranks[y*n : (y+1)*n]
+	}
+	return matrix
+}
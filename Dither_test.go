@@ -0,0 +1,163 @@
+package Netpbm
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// blackFraction returns the share of black pixels of the PBM image.
+func blackFraction(pbm *PBM) float64 {
+	black := 0
+	for y := range pbm.data {
+		for _, b := range pbm.data[y] {
+			if b {
+				black++
+			}
+		}
+	}
+	return float64(black) / float64(pbm.width*pbm.height)
+}
+
+func TestDitherThreshold(t *testing.T) {
+	tests := []struct {
+		name string
+		pgm  *PGM
+	}{
+		{"random", randomPGM(31, 17, 256, 1)},
+		{"around the middle", &PGM{data: [][]uint8{{0, 126, 127, 128, 129, 255}}, width: 6, height: 1, magicNumber: "P2", max: 255}},
+		{"odd max", &PGM{data: [][]uint8{{0, 1, 2, 3}}, width: 4, height: 1, magicNumber: "P2", max: 3}},
+		{"even max", &PGM{data: [][]uint8{{0, 1, 2, 3, 4}}, width: 5, height: 1, magicNumber: "P2", max: 4}},
+	}
+	for _, tt := range tests {
+		got := tt.pgm.ToPBMDither(DitherOptions{Method: DitherThreshold})
+		for y := range tt.pgm.data {
+			for x, v := range tt.pgm.data[y] {
+				want := float64(v) < float64(tt.pgm.max)/2
+				if got.data[y][x] != want {
+					t.Errorf("%s: sample %d of max %d gives %v, want %v", tt.name, v, tt.pgm.max, got.data[y][x], want)
+				}
+			}
+		}
+	}
+}
+
+func TestPPMToPBM(t *testing.T) {
+	ppm := randomPPM(23, 11, 1)
+	got := ppm.ToPBM()
+	if want := ppm.ToPBMDither(DitherOptions{Method: DitherThreshold}); !reflect.DeepEqual(got.data, want.data) {
+		t.Error("ToPBM does not match ToPBMDither with DitherThreshold")
+	}
+	gray := ppm.ToPGM()
+	for y := range gray.data {
+		for x, v := range gray.data[y] {
+			if want := float64(v) < 127.5; got.data[y][x] != want {
+				t.Fatalf("pixel (%d, %d) of luminance %d gives %v, want %v", x, y, v, got.data[y][x], want)
+			}
+		}
+	}
+}
+
+func TestDitherDiffusionDensity(t *testing.T) {
+	methods := []struct {
+		name      string
+		method    DitherMethod
+		levels    []uint8
+		tolerance float64
+	}{
+		{"Floyd-Steinberg", DitherFloydSteinberg, []uint8{32, 64, 128, 192, 224}, 0.02},
+		{"Jarvis-Judice-Ninke", DitherJarvisJudiceNinke, []uint8{32, 64, 128, 192, 224}, 0.02},
+		{"Stucki", DitherStucki, []uint8{32, 64, 128, 192, 224}, 0.02},
+		// Atkinson drops a quarter of the error, which washes out the
+		// lightest and darkest tones, so only check the midtones
+		{"Atkinson", DitherAtkinson, []uint8{96, 128, 160}, 0.05},
+	}
+	for _, m := range methods {
+		for _, serpentine := range []bool{false, true} {
+			for _, level := range m.levels {
+				pbm := flatPGM(64, 64, level, 255).ToPBMDither(DitherOptions{Method: m.method, Serpentine: serpentine})
+				want := 1 - float64(level)/255
+				if got := blackFraction(pbm); math.Abs(got-want) > m.tolerance {
+					t.Errorf("%s, serpentine %v, level %d: %.3f black, want %.3f", m.name, serpentine, level, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestOrderedMatrices(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix [][]float64
+		size   int
+	}{
+		{"Bayer 2", bayerMatrix(2), 2},
+		{"Bayer 4", bayerMatrix(4), 4},
+		{"Bayer 8", bayerMatrix(8), 8},
+		{"Bayer 16", bayerMatrix(16), 16},
+		{"Bayer default", bayerMatrix(0), 4},
+		{"clustered dot 5", clusteredDotMatrix(5), 5},
+		{"clustered dot 8", clusteredDotMatrix(8), 8},
+	}
+	for _, tt := range tests {
+		if len(tt.matrix) != tt.size {
+			t.Errorf("%s: got size %d, want %d", tt.name, len(tt.matrix), tt.size)
+			continue
+		}
+		// Every threshold (r + 0.5) / n² appears once
+		var ranks []int
+		for _, row := range tt.matrix {
+			for _, v := range row {
+				ranks = append(ranks, int(math.Round(v*float64(tt.size*tt.size)-0.5)))
+			}
+		}
+		sort.Ints(ranks)
+		for i, r := range ranks {
+			if r != i {
+				t.Errorf("%s: thresholds are not a permutation of the ranks: %v", tt.name, ranks)
+				break
+			}
+		}
+	}
+}
+
+func TestBayerDensity(t *testing.T) {
+	// A quarter gray turns exactly three quarters of every 4x4 cell black
+	pbm := flatPGM(16, 16, 64, 255).ToPBMDither(DitherOptions{Method: DitherBayer})
+	for cy := 0; cy < 16; cy += 4 {
+		for cx := 0; cx < 16; cx += 4 {
+			black := 0
+			for y := cy; y < cy+4; y++ {
+				for x := cx; x < cx+4; x++ {
+					if pbm.data[y][x] {
+						black++
+					}
+				}
+			}
+			if black != 12 {
+				t.Errorf("cell (%d, %d) has %d black pixels, want 12", cx, cy, black)
+			}
+		}
+	}
+}
+
+func TestClusteredDotGrowsFromCenter(t *testing.T) {
+	// On a light gray only the few pixels at the center of each cell are black
+	pbm := flatPGM(8, 8, 242, 255).ToPBMDither(DitherOptions{Method: DitherClusteredDot})
+	black := 0
+	for y := range pbm.data {
+		for x, b := range pbm.data[y] {
+			if !b {
+				continue
+			}
+			black++
+			if dx, dy := float64(x)-3.5, float64(y)-3.5; dx*dx+dy*dy > 2*1.5*1.5 {
+				t.Errorf("pixel (%d, %d) far from the center is black", x, y)
+			}
+		}
+	}
+	if black == 0 {
+		t.Error("no pixel is black")
+	}
+}
@@ -0,0 +1,215 @@
+package Netpbm
+
+import "math"
+
+// Horizontal derivative kernels, scaled so that a step of height max gives a
+// response of max. The vertical kernels are their transposes.
+var (
+	sobelKernel = [][]float64{
+		{-1.0 / 4, 0, 1.0 / 4},
+		{-2.0 / 4, 0, 2.0 / 4},
+		{-1.0 / 4, 0, 1.0 / 4},
+	}
+	scharrKernel = [][]float64{
+		{-3.0 / 16, 0, 3.0 / 16},
+		{-10.0 / 16, 0, 10.0 / 16},
+		{-3.0 / 16, 0, 3.0 / 16},
+	}
+	prewittKernel = [][]float64{
+		{-1.0 / 3, 0, 1.0 / 3},
+		{-1.0 / 3, 0, 1.0 / 3},
+		{-1.0 / 3, 0, 1.0 / 3},
+	}
+	laplacianKernel = [][]float64{
+		{0, 1, 0},
+		{1, -4, 1},
+		{0, 1, 0},
+	}
+)
+
+// transpose returns the weights mirrored along their diagonal.
+func transpose(w [][]float64) [][]float64 {
+	t := make([][]float64, len(w[0]))
+	for i := range t {
+		t[i] = make([]float64, len(w))
+		for j := range w {
+			t[i][j] = w[j][i]
+		}
+	}
+	return t
+}
+
+// gradients returns the horizontal and vertical derivatives of the channel
+// computed with the given horizontal kernel.
+func gradients(c channel, k [][]float64) (gx, gy channel) {
+	return convolveChannel(c, k, EdgeClamp), convolveChannel(c, transpose(k), EdgeClamp)
+}
+
+// magnitude returns the length of the gradient at every pixel.
+func magnitude(gx, gy channel) channel {
+	width, height := gx.size()
+	m := newChannel(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			m[y][x] = math.Hypot(gx[y][x], gy[y][x])
+		}
+	}
+	return m
+}
+
+// edgePGM builds a PGM image of the same size, magic number and max value as
+// the PGM image from the given channel.
+func (pgm *PGM) edgePGM(c channel) *PGM {
+	return &PGM{
+		data:        c.toUint8(pgm.max),
+		width:       pgm.width,
+		height:      pgm.height,
+		magicNumber: pgm.magicNumber,
+		max:         pgm.max,
+	}
+}
+
+// Sobel returns the gradient magnitude of the PGM image computed with the
+// Sobel operator. A sharp step from 0 to max gives max.
+func (pgm *PGM) Sobel() *PGM {
+	return pgm.edgePGM(magnitude(gradients(pgm.channel(), sobelKernel)))
+}
+
+// Scharr returns the gradient magnitude of the PGM image computed with the
+// Scharr operator, which is more accurate than Sobel for diagonal edges.
+func (pgm *PGM) Scharr() *PGM {
+	return pgm.edgePGM(magnitude(gradients(pgm.channel(), scharrKernel)))
+}
+
+// Prewitt returns the gradient magnitude of the PGM image computed with the
+// Prewitt operator.
+func (pgm *PGM) Prewitt() *PGM {
+	return pgm.edgePGM(magnitude(gradients(pgm.channel(), prewittKernel)))
+}
+
+// LaplacianOfGaussian returns the absolute response of the Laplacian to the
+// PGM image blurred with a Gaussian of standard deviation sigma. Edges show
+// as pairs of bright lines around the zero crossings.
+func (pgm *PGM) LaplacianOfGaussian(sigma float64) *PGM {
+	c := convolveChannel(gaussianBlurChannel(pgm.channel(), sigma), laplacianKernel, EdgeClamp)
+	for y := range c {
+		for x := range c[y] {
+			c[y][x] = math.Abs(c[y][x])
+		}
+	}
+	return pgm.edgePGM(c)
+}
+
+// Canny returns the edges of the PGM image found by the Canny detector. The
+// image is blurred with a Gaussian of standard deviation sigma, the Sobel
+// gradient is thinned to its local maxima along its direction, then pixels
+// whose gradient reaches high are kept along with the pixels above low that
+// are connected to them. Edges are black in the result.
+func (pgm *PGM) Canny(sigma float64, low, high uint8) *PBM {
+	if low > high {
+		low, high = high, low
+	}
+	gx, gy := gradients(gaussianBlurChannel(pgm.channel(), sigma), sobelKernel)
+	m := magnitude(gx, gy)
+	width, height := m.size()
+
+	// Keep the pixels that are maxima across the edge
+	thin := newChannel(width, height)
+	parallelRows(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < width; x++ {
+				v := m[y][x]
+				if v == 0 {
+					continue
+				}
+				// Round the gradient direction to one of four neighbour pairs
+				dx, dy := 1, 0
+				angle := math.Atan2(gy[y][x], gx[y][x]) * 180 / math.Pi
+				if angle < 0 {
+					angle += 180
+				}
+				switch {
+				case angle >= 22.5 && angle < 67.5:
+					dx, dy = 1, 1
+				case angle >= 67.5 && angle < 112.5:
+					dx, dy = 0, 1
+				case angle >= 112.5 && angle < 157.5:
+					dx, dy = -1, 1
+				}
+				at := func(x, y int) float64 {
+					if x < 0 || y < 0 || x >= width || y >= height {
+						return 0
+					}
+					return m[y][x]
+				}
+				if v >= at(x+dx, y+dy) && v > at(x-dx, y-dy) {
+					thin[y][x] = v
+				}
+			}
+		}
+	})
+
+	pbm := &PBM{
+		data:        make([][]bool, height),
+		width:       width,
+		height:      height,
+		magicNumber: "P1",
+	}
+	for y := range pbm.data {
+		pbm.data[y] = make([]bool, width)
+	}
+
+	// Follow weak edges from every strong pixel
+	var stack []Point
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if thin[y][x] >= float64(high) && thin[y][x] > 0 {
+				pbm.data[y][x] = true
+				stack = append(stack, Point{x, y})
+			}
+		}
+	}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for ny := max(p.Y-1, 0); ny <= min(p.Y+1, height-1); ny++ {
+			for nx := max(p.X-1, 0); nx <= min(p.X+1, width-1); nx++ {
+				if !pbm.data[ny][nx] && thin[ny][nx] >= float64(low) && thin[ny][nx] > 0 {
+					pbm.data[ny][nx] = true
+					stack = append(stack, Point{nx, ny})
+				}
+			}
+		}
+	}
+	return pbm
+}
+
+// Sobel returns the gradient magnitude of the luminance of the PPM image
+// computed with the Sobel operator.
+func (ppm *PPM) Sobel() *PGM {
+	return ppm.ToPGM().Sobel()
+}
+
+// Scharr returns the gradient magnitude of the luminance of the PPM image
+// computed with the Scharr operator.
+func (ppm *PPM) Scharr() *PGM {
+	return ppm.ToPGM().Scharr()
+}
+
+// Prewitt returns the gradient magnitude of the luminance of the PPM image
+// computed with the Prewitt operator.
+func (ppm *PPM) Prewitt() *PGM {
+	return ppm.ToPGM().Prewitt()
+}
+
+// LaplacianOfGaussian returns the absolute Laplacian of Gaussian response of
+// the luminance of the PPM image.
+func (ppm *PPM) LaplacianOfGaussian(sigma float64) *PGM {
+	return ppm.ToPGM().LaplacianOfGaussian(sigma)
+}
+
+// Canny returns the edges of the luminance of the PPM image found by the
+// Canny detector, like PGM.Canny.
+func (ppm *PPM) Canny(sigma float64, low, high uint8) *PBM {
+	return ppm.ToPGM().Canny(sigma, low, high)
+}
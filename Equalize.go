@@ -0,0 +1,180 @@
+package Netpbm
+
+import "math"
+
+// equalizeTable returns the lookup table that spreads the values of the
+// histogram evenly over [0, max].
+func (h Histogram) equalizeTable(max uint8) []uint8 {
+	table := make([]uint8, len(h))
+	total := h.Total()
+	cdfMin := h[h.Min()]
+	if total == cdfMin {
+		// A single value cannot be spread, keep it as it is
+		for v := range table {
+			table[v] = uint8(v)
+		}
+		return table
+	}
+	cdf := 0
+	for v, n := range h {
+		cdf += n
+		scaled := float64(cdf-cdfMin) / float64(total-cdfMin) * float64(max)
+		table[v] = clampSample(scaled, max)
+	}
+	return table
+}
+
+// setLuminance rescales each pixel of the PPM image so that its luminance
+// becomes the matching value of lum, which keeps the hue of the pixels.
+func (ppm *PPM) setLuminance(lum [][]uint8) {
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			pixel := ppm.data[y][x]
+			old := luma(pixel)
+			if old == 0 {
+				ppm.data[y][x] = Pixel{lum[y][x], lum[y][x], lum[y][x]}
+				continue
+			}
+			ratio := float64(lum[y][x]) / float64(old)
+			ppm.data[y][x] = Pixel{
+				R: clampSample(float64(pixel.R)*ratio, ppm.max),
+				G: clampSample(float64(pixel.G)*ratio, ppm.max),
+				B: clampSample(float64(pixel.B)*ratio, ppm.max),
+			}
+		}
+	}
+}
+
+// luminance returns the Rec. 601 luminance of every pixel of the PPM image.
+func (ppm *PPM) luminance() [][]uint8 {
+	lum := make([][]uint8, ppm.height)
+	for y := 0; y < ppm.height; y++ {
+		lum[y] = make([]uint8, ppm.width)
+		for x := 0; x < ppm.width; x++ {
+			lum[y][x] = luma(ppm.data[y][x])
+		}
+	}
+	return lum
+}
+
+// Equalize spreads the gray levels of the PGM image evenly over [0, max]
+// to improve its contrast.
+func (pgm *PGM) Equalize() {
+	table := pgm.Histogram().equalizeTable(pgm.max)
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pgm.data[y][x] = table[min(int(pgm.data[y][x]), len(table)-1)]
+		}
+	}
+}
+
+// Equalize spreads the luminance of the PPM image evenly over [0, max]
+// to improve its contrast. Colors are scaled together so hues are kept.
+func (ppm *PPM) Equalize() {
+	table := ppm.Histogram().Luminance.equalizeTable(ppm.max)
+	lum := ppm.luminance()
+	for y := range lum {
+		for x := range lum[y] {
+			lum[y][x] = table[min(int(lum[y][x]), len(table)-1)]
+		}
+	}
+	ppm.setLuminance(lum)
+}
+
+// clahe applies contrast-limited adaptive histogram equalization to the
+// samples. Each tile of the tilesX x tilesY grid is equalized on its own with
+// its histogram clipped at clipLimit times the average bin count, and the
+// tables of neighbouring tiles are blended to avoid visible seams.
+func clahe(data [][]uint8, width, height int, maxValue uint8, tilesX, tilesY int, clipLimit float64) [][]uint8 {
+	tilesX = min(max(tilesX, 1), max(width, 1))
+	tilesY = min(max(tilesY, 1), max(height, 1))
+	bins := int(maxValue) + 1
+
+	// Build the equalization table of every tile
+	tables := make([][][]uint8, tilesY)
+	for ty := 0; ty < tilesY; ty++ {
+		tables[ty] = make([][]uint8, tilesX)
+		y0, y1 := ty*height/tilesY, (ty+1)*height/tilesY
+		for tx := 0; tx < tilesX; tx++ {
+			x0, x1 := tx*width/tilesX, (tx+1)*width/tilesX
+			h := make(Histogram, bins)
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					h.add(data[y][x])
+				}
+			}
+			if clipLimit > 0 {
+				h.clip(int(math.Max(1, clipLimit*float64((x1-x0)*(y1-y0))/float64(bins))))
+			}
+			tables[ty][tx] = h.equalizeTable(maxValue)
+		}
+	}
+
+	tileWidth := float64(width) / float64(tilesX)
+	tileHeight := float64(height) / float64(tilesY)
+	out := make([][]uint8, height)
+	parallelRows(height, func(r0, r1 int) {
+		for y := r0; y < r1; y++ {
+			out[y] = make([]uint8, width)
+			ty0, ty1, wy := tileBlend((float64(y)+0.5)/tileHeight-0.5, tilesY)
+			for x := 0; x < width; x++ {
+				tx0, tx1, wx := tileBlend((float64(x)+0.5)/tileWidth-0.5, tilesX)
+				v := min(int(data[y][x]), bins-1)
+				top := float64(tables[ty0][tx0][v])*(1-wx) + float64(tables[ty0][tx1][v])*wx
+				bottom := float64(tables[ty1][tx0][v])*(1-wx) + float64(tables[ty1][tx1][v])*wx
+				out[y][x] = clampSample(top*(1-wy)+bottom*wy, maxValue)
+			}
+		}
+	})
+	return out
+}
+
+// tileBlend returns the two tiles around the position t, measured in tiles
+// from the center of the first tile, and the weight of the second one.
+func tileBlend(t float64, tiles int) (int, int, float64) {
+	if t <= 0 {
+		return 0, 0, 0
+	}
+	i := int(t)
+	if i >= tiles-1 {
+		return tiles - 1, tiles - 1, 0
+	}
+	return i, i + 1, t - float64(i)
+}
+
+// clip limits every bin of the histogram to limit, spreading the excess
+// evenly over all bins.
+func (h Histogram) clip(limit int) {
+	excess := 0
+	for v, n := range h {
+		if n > limit {
+			excess += n - limit
+			h[v] = limit
+		}
+	}
+	share, rest := excess/len(h), excess%len(h)
+	for v := range h {
+		h[v] += share
+	}
+	// Spread the remainder across the whole range rather than on the first
+	// bins, so that a flat tile keeps about the same value
+	step := max(len(h)/max(rest, 1), 1)
+	for v := 0; v < len(h) && rest > 0; v += step {
+		h[v]++
+		rest--
+	}
+}
+
+// CLAHE enhances the local contrast of the PGM image with contrast-limited
+// adaptive histogram equalization over a tilesX x tilesY grid. clipLimit is
+// the highest bin count allowed, as a multiple of the average bin count;
+// 0 disables clipping, which gives plain adaptive equalization.
+func (pgm *PGM) CLAHE(tilesX, tilesY int, clipLimit float64) {
+	pgm.data = clahe(pgm.data, pgm.width, pgm.height, pgm.max, tilesX, tilesY, clipLimit)
+}
+
+// CLAHE enhances the local contrast of the PPM image like PGM.CLAHE, working
+// on the luminance so that hues are kept.
+func (ppm *PPM) CLAHE(tilesX, tilesY int, clipLimit float64) {
+	ppm.setLuminance(clahe(ppm.luminance(), ppm.width, ppm.height, ppm.max, tilesX, tilesY, clipLimit))
+}
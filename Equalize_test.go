@@ -0,0 +1,112 @@
+package Netpbm
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// naiveEqualize maps every sample through the normalized cumulative
+// histogram of the PGM image.
+func naiveEqualize(pgm *PGM) [][]uint8 {
+	below := func(v uint8) int {
+		n := 0
+		for y := range pgm.data {
+			for _, s := range pgm.data[y] {
+				if s <= v {
+					n++
+				}
+			}
+		}
+		return n
+	}
+	lowest := pgm.data[0][0]
+	for y := range pgm.data {
+		for _, s := range pgm.data[y] {
+			lowest = min(lowest, s)
+		}
+	}
+	total, cdfMin := pgm.width*pgm.height, below(lowest)
+	out := make([][]uint8, pgm.height)
+	for y := range out {
+		out[y] = make([]uint8, pgm.width)
+		for x, s := range pgm.data[y] {
+			if total == cdfMin {
+				out[y][x] = s
+				continue
+			}
+			out[y][x] = uint8(math.Round(float64(below(s)-cdfMin) / float64(total-cdfMin) * float64(pgm.max)))
+		}
+	}
+	return out
+}
+
+func TestEqualize(t *testing.T) {
+	tests := []struct {
+		name string
+		pgm  *PGM
+	}{
+		{"four levels", &PGM{data: [][]uint8{{10, 20}, {30, 40}}, width: 2, height: 2, magicNumber: "P2", max: 255}},
+		{"flat", flatPGM(3, 2, 77, 255)},
+		{"few levels", randomPGM(16, 9, 5, 1)},
+		{"many levels", randomPGM(16, 9, 256, 2)},
+		{"small max", &PGM{data: [][]uint8{{0, 1, 1, 3}}, width: 4, height: 1, magicNumber: "P2", max: 3}},
+	}
+	for _, tt := range tests {
+		want := naiveEqualize(tt.pgm)
+		tt.pgm.Equalize()
+		if !reflect.DeepEqual(tt.pgm.data, want) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.pgm.data, want)
+		}
+	}
+
+	pgm := &PGM{data: [][]uint8{{10, 20}, {30, 40}}, width: 2, height: 2, magicNumber: "P2", max: 255}
+	pgm.Equalize()
+	if want := [][]uint8{{0, 85}, {170, 255}}; !reflect.DeepEqual(pgm.data, want) {
+		t.Errorf("got %v, want %v", pgm.data, want)
+	}
+}
+
+func TestCLAHESingleTile(t *testing.T) {
+	// A single tile without clipping is plain equalization
+	pgm := randomPGM(20, 11, 256, 3)
+	want := naiveEqualize(pgm)
+	pgm.CLAHE(1, 1, 0)
+	if !reflect.DeepEqual(pgm.data, want) {
+		t.Error("CLAHE with one tile and no clipping does not match Equalize")
+	}
+}
+
+func TestCLAHEFlat(t *testing.T) {
+	for _, value := range []uint8{0, 50, 100, 200, 255} {
+		pgm := flatPGM(16, 16, value, 255)
+		pgm.CLAHE(2, 2, 2)
+		for y := range pgm.data {
+			for x, v := range pgm.data[y] {
+				if math.Abs(float64(v)-float64(value)) > 8 {
+					t.Fatalf("flat %d: pixel (%d, %d) became %d", value, x, y, v)
+				}
+			}
+		}
+	}
+}
+
+func TestHistogramClip(t *testing.T) {
+	tests := []struct {
+		name  string
+		h     Histogram
+		limit int
+		want  Histogram
+	}{
+		{"under the limit", Histogram{1, 2, 3}, 3, Histogram{1, 2, 3}},
+		{"even share", Histogram{0, 9, 0}, 3, Histogram{2, 5, 2}},
+		{"spread remainder", Histogram{0, 0, 0, 6}, 4, Histogram{1, 0, 1, 4}},
+	}
+	for _, tt := range tests {
+		total := tt.h.Total()
+		tt.h.clip(tt.limit)
+		if !reflect.DeepEqual(tt.h, tt.want) || tt.h.Total() != total {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.h, tt.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package Netpbm
+
+import "math/rand"
+
+// randomPGM returns a PGM image of the given size with a max value of 255
+// and random samples below levels.
+func randomPGM(width, height, levels int, seed int64) *PGM {
+	rng := rand.New(rand.NewSource(seed))
+	pgm := &PGM{data: make([][]uint8, height), width: width, height: height, magicNumber: "P2", max: 255}
+	for y := range pgm.data {
+		pgm.data[y] = make([]uint8, width)
+		for x := range pgm.data[y] {
+			pgm.data[y][x] = uint8(rng.Intn(levels))
+		}
+	}
+	return pgm
+}
+
+// randomPPM returns a PPM image of the given size with a max value of 255
+// and random samples.
+func randomPPM(width, height int, seed int64) *PPM {
+	rng := rand.New(rand.NewSource(seed))
+	ppm := &PPM{data: make([][]Pixel, height), width: width, height: height, magicNumber: "P3", max: 255}
+	for y := range ppm.data {
+		ppm.data[y] = make([]Pixel, width)
+		for x := range ppm.data[y] {
+			ppm.data[y][x] = Pixel{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256))}
+		}
+	}
+	return ppm
+}
+
+// flatPGM returns a PGM image filled with value.
+func flatPGM(width, height int, value, max uint8) *PGM {
+	pgm := &PGM{data: make([][]uint8, height), width: width, height: height, magicNumber: "P2", max: max}
+	for y := range pgm.data {
+		pgm.data[y] = make([]uint8, width)
+		for x := range pgm.data[y] {
+			pgm.data[y][x] = value
+		}
+	}
+	return pgm
+}
+
+// copyPGM returns a deep copy of the PGM image.
+func copyPGM(pgm *PGM) *PGM {
+	out := *pgm
+	out.data = make([][]uint8, len(pgm.data))
+	for y := range pgm.data {
+		out.data[y] = append([]uint8(nil), pgm.data[y]...)
+	}
+	return &out
+}
+
+// samePGM reports whether the two PGM images have the same size and samples.
+func samePGM(a, b *PGM) bool {
+	if a.width != b.width || a.height != b.height {
+		return false
+	}
+	for y := range a.data {
+		for x := range a.data[y] {
+			if a.data[y][x] != b.data[y][x] {
+				return false
+			}
+		}
+	}
+	return true
+}
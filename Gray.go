@@ -0,0 +1,116 @@
+package Netpbm
+
+import "math"
+
+// GrayMethod selects how PPM.ToPGMWith turns a color into a gray level.
+type GrayMethod int
+
+const (
+	// GrayRec601 weights the channels with the Rec. 601 luma coefficients,
+	// 0.299 R + 0.587 G + 0.114 B, like netpbm's ppmtopgm. It is the default.
+	GrayRec601 GrayMethod = iota
+	// GrayRec709 weights the channels with the Rec. 709 (HDTV) luma coefficients.
+	GrayRec709
+	// GrayLinear decodes sRGB to linear light, computes the Rec. 709
+	// luminance there and encodes the result back with the sRGB curve.
+	GrayLinear
+	// GrayLightness takes the average of the strongest and weakest channels, as in HSL.
+	GrayLightness
+	// GrayAverage takes the unweighted average of the three channels.
+	GrayAverage
+	// GrayRed keeps only the red channel.
+	GrayRed
+	// GrayGreen keeps only the green channel.
+	GrayGreen
+	// GrayBlue keeps only the blue channel.
+	GrayBlue
+)
+
+// newPGMFrom builds a PGM image of the same size and max value as the PPM
+// image, computing each gray level with f.
+func (ppm *PPM) newPGMFrom(f func(p Pixel) float64) *PGM {
+	pgm := &PGM{
+		data:        make([][]uint8, ppm.height),
+		width:       ppm.width,
+		height:      ppm.height,
+		magicNumber: "P2",
+		max:         ppm.max,
+	}
+	for y := 0; y < ppm.height; y++ {
+		pgm.data[y] = make([]uint8, ppm.width)
+		for x := 0; x < ppm.width; x++ {
+			pgm.data[y][x] = clampSample(f(ppm.data[y][x]), ppm.max)
+		}
+	}
+	return pgm
+}
+
+// ToPGMWith converts the PPM image to PGM using the given method.
+func (ppm *PPM) ToPGMWith(method GrayMethod) *PGM {
+	switch method {
+	case GrayRec709:
+		return ppm.ToPGMWeighted(0.2126, 0.7152, 0.0722)
+	case GrayLinear:
+		return ppm.linearGray()
+	case GrayLightness:
+		return ppm.newPGMFrom(func(p Pixel) float64 {
+			hi := max(p.R, p.G, p.B)
+			lo := min(p.R, p.G, p.B)
+			return (float64(hi) + float64(lo)) / 2
+		})
+	case GrayAverage:
+		return ppm.ToPGMWeighted(1, 1, 1)
+	case GrayRed:
+		return ppm.ToPGMWeighted(1, 0, 0)
+	case GrayGreen:
+		return ppm.ToPGMWeighted(0, 1, 0)
+	case GrayBlue:
+		return ppm.ToPGMWeighted(0, 0, 1)
+	}
+	return ppm.ToPGMWeighted(0.299, 0.587, 0.114)
+}
+
+// ToPGMWeighted converts the PPM image to PGM with custom channel weights.
+// The weights are normalized so that they add up to 1.
+func (ppm *PPM) ToPGMWeighted(r, g, b float64) *PGM {
+	sum := r + g + b
+	if sum == 0 {
+		sum = 1
+	}
+	r, g, b = r/sum, g/sum, b/sum
+	return ppm.newPGMFrom(func(p Pixel) float64 {
+		return r*float64(p.R) + g*float64(p.G) + b*float64(p.B)
+	})
+}
+
+// linearGray computes the luminance of the PPM image in linear light.
+func (ppm *PPM) linearGray() *PGM {
+	// Decode every possible sample once
+	decode := make([]float64, int(ppm.max)+1)
+	for v := range decode {
+		decode[v] = srgbToLinear(float64(v) / math.Max(float64(ppm.max), 1))
+	}
+	at := func(v uint8) float64 {
+		return decode[min(int(v), len(decode)-1)]
+	}
+	return ppm.newPGMFrom(func(p Pixel) float64 {
+		y := 0.2126*at(p.R) + 0.7152*at(p.G) + 0.0722*at(p.B)
+		return linearToSRGB(y) * float64(ppm.max)
+	})
+}
+
+// srgbToLinear decodes an sRGB value in [0, 1] to linear light.
+func srgbToLinear(c float64) float64 {
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB encodes a linear light value in [0, 1] with the sRGB curve.
+func linearToSRGB(c float64) float64 {
+	if c <= 0.0031308 {
+		return c * 12.92
+	}
+	return 1.055*math.Pow(c, 1/2.4) - 0.055
+}
@@ -0,0 +1,195 @@
+package Netpbm
+
+import "errors"
+
+// grayMorph returns, for every pixel of the PGM image, the smallest (erode)
+// or largest sample under the flat structuring element. Dilation uses the
+// element mirrored around its center, and pixels outside of the image are
+// ignored.
+func (pgm *PGM) grayMorph(se *PBM, erode bool) [][]uint8 {
+	points := elementOffsets(se)
+	if !erode {
+		for i := range points {
+			points[i] = Point{-points[i].X, -points[i].Y}
+		}
+	}
+	dst := make([][]uint8, pgm.height)
+	parallelRows(pgm.height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := make([]uint8, pgm.width)
+			// Start from the neutral value so that outside pixels never win
+			start := uint8(0)
+			if erode {
+				start = pgm.max
+			}
+			for x := range row {
+				row[x] = start
+			}
+			for _, p := range points {
+				sy := y + p.Y
+				if sy < 0 || sy >= pgm.height {
+					continue
+				}
+				src := pgm.data[sy]
+				for x := max(0, -p.X); x < min(pgm.width, pgm.width-p.X); x++ {
+					v := src[x+p.X]
+					if erode && v < row[x] || !erode && v > row[x] {
+						row[x] = v
+					}
+				}
+			}
+			dst[y] = row
+		}
+	})
+	return dst
+}
+
+// Erode replaces every pixel of the PGM image by the darkest pixel under the
+// flat structuring element, whose black pixels form the shape and whose
+// center is at (width/2, height/2). Bright features shrink.
+func (pgm *PGM) Erode(se *PBM) {
+	pgm.data = pgm.grayMorph(se, true)
+}
+
+// Dilate replaces every pixel of the PGM image by the brightest pixel under
+// the flat structuring element. Bright features grow.
+func (pgm *PGM) Dilate(se *PBM) {
+	pgm.data = pgm.grayMorph(se, false)
+}
+
+// Open erodes then dilates the PGM image, which removes the bright details
+// smaller than the structuring element.
+func (pgm *PGM) Open(se *PBM) {
+	pgm.Erode(se)
+	pgm.Dilate(se)
+}
+
+// Close dilates then erodes the PGM image, which removes the dark details
+// smaller than the structuring element.
+func (pgm *PGM) Close(se *PBM) {
+	pgm.Dilate(se)
+	pgm.Erode(se)
+}
+
+// TopHat keeps the bright details of the PGM image smaller than the
+// structuring element, by subtracting its opening from it. With a large
+// element this removes an uneven background.
+func (pgm *PGM) TopHat(se *PBM) {
+	original := pgm.data
+	pgm.Open(se)
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pgm.data[y][x] = original[y][x] - pgm.data[y][x]
+		}
+	}
+}
+
+// BlackTopHat keeps the dark details of the PGM image smaller than the
+// structuring element, by subtracting the image from its closing. The
+// details come out bright.
+func (pgm *PGM) BlackTopHat(se *PBM) {
+	original := pgm.data
+	pgm.Close(se)
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pgm.data[y][x] -= original[y][x]
+		}
+	}
+}
+
+// Reconstruct replaces the PGM image, used as marker, by its morphological
+// reconstruction by dilation under mask: the marker is dilated over and over
+// with 8-connectivity, without ever exceeding the mask, until it stops
+// changing. The images must have the same size. It uses Vincent's hybrid
+// algorithm, two raster scans followed by a queue.
+func (pgm *PGM) Reconstruct(mask *PGM) error {
+	if mask.width != pgm.width || mask.height != pgm.height {
+		return errors.New("mask size does not match image size")
+	}
+	width, height := pgm.width, pgm.height
+	j := make([][]uint8, height)
+	for y := range j {
+		j[y] = make([]uint8, width)
+		for x := range j[y] {
+			j[y][x] = min(pgm.data[y][x], mask.data[y][x])
+		}
+	}
+	inside := func(x, y int) bool { return x >= 0 && y >= 0 && x < width && y < height }
+
+	// Neighbours visited before a pixel in raster order
+	before := []Point{{-1, -1}, {0, -1}, {1, -1}, {-1, 0}}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := j[y][x]
+			for _, d := range before {
+				if inside(x+d.X, y+d.Y) {
+					v = max(v, j[y+d.Y][x+d.X])
+				}
+			}
+			j[y][x] = min(v, mask.data[y][x])
+		}
+	}
+
+	var queue []Point
+	for y := height - 1; y >= 0; y-- {
+		for x := width - 1; x >= 0; x-- {
+			v := j[y][x]
+			for _, d := range before {
+				if inside(x-d.X, y-d.Y) {
+					v = max(v, j[y-d.Y][x-d.X])
+				}
+			}
+			v = min(v, mask.data[y][x])
+			j[y][x] = v
+			for _, d := range before {
+				qx, qy := x-d.X, y-d.Y
+				if inside(qx, qy) && j[qy][qx] < v && j[qy][qx] < mask.data[qy][qx] {
+					queue = append(queue, Point{x, y})
+					break
+				}
+			}
+		}
+	}
+
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for qy := p.Y - 1; qy <= p.Y+1; qy++ {
+			for qx := p.X - 1; qx <= p.X+1; qx++ {
+				if !inside(qx, qy) {
+					continue
+				}
+				if j[qy][qx] < j[p.Y][p.X] && j[qy][qx] != mask.data[qy][qx] {
+					j[qy][qx] = min(j[p.Y][p.X], mask.data[qy][qx])
+					queue = append(queue, Point{qx, qy})
+				}
+			}
+		}
+	}
+	pgm.data = j
+	return nil
+}
+
+// ReconstructByErosion replaces the PGM image, used as marker, by its
+// reconstruction by erosion over mask, the dual of Reconstruct: the marker
+// is eroded without going below the mask. It fills the dark regions that do
+// not touch the marker's dark seeds, such as holes when the marker is the
+// max value everywhere but on the border.
+func (pgm *PGM) ReconstructByErosion(mask *PGM) error {
+	if mask.width != pgm.width || mask.height != pgm.height {
+		return errors.New("mask size does not match image size")
+	}
+	inverted := &PGM{data: make([][]uint8, mask.height), width: mask.width, height: mask.height, max: pgm.max}
+	for y := range inverted.data {
+		inverted.data[y] = make([]uint8, mask.width)
+		for x := range inverted.data[y] {
+			inverted.data[y][x] = pgm.max - min(mask.data[y][x], pgm.max)
+		}
+	}
+	pgm.Invert()
+	if err := pgm.Reconstruct(inverted); err != nil {
+		return err
+	}
+	pgm.Invert()
+	return nil
+}
@@ -0,0 +1,110 @@
+package Netpbm
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// naiveReconstruct dilates the marker with a 3x3 square and clips it to the
+// mask until it stops changing.
+func naiveReconstruct(marker, mask *PGM) [][]uint8 {
+	j := make([][]uint8, marker.height)
+	for y := range j {
+		j[y] = make([]uint8, marker.width)
+		for x := range j[y] {
+			j[y][x] = min(marker.data[y][x], mask.data[y][x])
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		next := make([][]uint8, len(j))
+		for y := range j {
+			next[y] = make([]uint8, len(j[y]))
+			for x := range j[y] {
+				v := j[y][x]
+				for ny := max(y-1, 0); ny <= min(y+1, len(j)-1); ny++ {
+					for nx := max(x-1, 0); nx <= min(x+1, len(j[y])-1); nx++ {
+						v = max(v, j[ny][nx])
+					}
+				}
+				next[y][x] = min(v, mask.data[y][x])
+				changed = changed || next[y][x] != j[y][x]
+			}
+		}
+		j = next
+	}
+	return j
+}
+
+func TestReconstruct(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		levels        int
+		seed          int64
+	}{
+		{"single pixel", 1, 1, 256, 1},
+		{"single row", 40, 1, 256, 2},
+		{"single column", 1, 40, 256, 3},
+		{"few levels", 37, 29, 3, 4},
+		{"many levels", 37, 29, 256, 5},
+		{"wide", 150, 7, 8, 6},
+	}
+	for _, tt := range tests {
+		mask := randomPGM(tt.width, tt.height, tt.levels, tt.seed)
+		// A sparse marker forces values to travel far through the queue
+		marker := randomPGM(tt.width, tt.height, 1, 0)
+		rng := rand.New(rand.NewSource(tt.seed))
+		for i := 0; i < 3; i++ {
+			marker.data[rng.Intn(tt.height)][rng.Intn(tt.width)] = 255
+		}
+		want := naiveReconstruct(marker, mask)
+		if err := marker.Reconstruct(mask); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for y := range want {
+			for x := range want[y] {
+				if marker.data[y][x] != want[y][x] {
+					t.Fatalf("%s: sample (%d, %d) is %d, want %d", tt.name, x, y, marker.data[y][x], want[y][x])
+				}
+			}
+		}
+	}
+}
+
+func TestReconstructSpiral(t *testing.T) {
+	// A one pixel wide corridor winding back and forth, so that the raster
+	// scans alone cannot carry the marker to its end
+	const size = 9
+	mask := randomPGM(size, size, 1, 0)
+	for y := 0; y < size; y += 2 {
+		for x := range mask.data[y] {
+			mask.data[y][x] = 200
+		}
+		if y+1 < size {
+			mask.data[y+1][(y/2%2)*(size-1)] = 200
+		}
+	}
+	marker := randomPGM(size, size, 1, 0)
+	marker.data[size-1][0] = 255
+	want := naiveReconstruct(marker, mask)
+	if err := marker.Reconstruct(mask); err != nil {
+		t.Fatal(err)
+	}
+	for y := range want {
+		for x := range want[y] {
+			if marker.data[y][x] != want[y][x] {
+				t.Fatalf("sample (%d, %d) is %d, want %d", x, y, marker.data[y][x], want[y][x])
+			}
+		}
+	}
+	if marker.data[0][0] != 200 {
+		t.Errorf("corridor start is %d, want 200", marker.data[0][0])
+	}
+}
+
+func TestReconstructSizeMismatch(t *testing.T) {
+	if err := randomPGM(3, 3, 1, 0).Reconstruct(randomPGM(3, 4, 1, 0)); err == nil {
+		t.Error("Reconstruct accepted a mask of a different size")
+	}
+}
@@ -0,0 +1,148 @@
+package Netpbm
+
+import "math"
+
+// Histogram counts the pixels of each sample value, the index being the value.
+type Histogram []int
+
+// ColorHistogram holds the histograms of the channels of a PPM image and of its luminance.
+type ColorHistogram struct {
+	R, G, B, Luminance Histogram
+}
+
+// add counts one pixel of value v, values above the max going in the last bin.
+func (h Histogram) add(v uint8) {
+	if int(v) >= len(h) {
+		v = uint8(len(h) - 1)
+	}
+	h[v]++
+}
+
+// Histogram returns the histogram of the PGM image, with max+1 bins.
+func (pgm *PGM) Histogram() Histogram {
+	h := make(Histogram, int(pgm.max)+1)
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			h.add(pgm.data[y][x])
+		}
+	}
+	return h
+}
+
+// Histogram returns the histograms of the red, green and blue channels of the
+// PPM image and of its Rec. 601 luminance, each with max+1 bins.
+func (ppm *PPM) Histogram() ColorHistogram {
+	bins := int(ppm.max) + 1
+	h := ColorHistogram{
+		R:         make(Histogram, bins),
+		G:         make(Histogram, bins),
+		B:         make(Histogram, bins),
+		Luminance: make(Histogram, bins),
+	}
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			pixel := ppm.data[y][x]
+			h.R.add(pixel.R)
+			h.G.add(pixel.G)
+			h.B.add(pixel.B)
+			h.Luminance.add(luma(pixel))
+		}
+	}
+	return h
+}
+
+// Total returns the number of pixels counted in the histogram.
+func (h Histogram) Total() int {
+	total := 0
+	for _, n := range h {
+		total += n
+	}
+	return total
+}
+
+// Min returns the smallest value present, or 0 if the histogram is empty.
+func (h Histogram) Min() int {
+	for v, n := range h {
+		if n > 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+// Max returns the largest value present, or 0 if the histogram is empty.
+func (h Histogram) Max() int {
+	for v := len(h) - 1; v >= 0; v-- {
+		if h[v] > 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+// Mean returns the average value.
+func (h Histogram) Mean() float64 {
+	total, sum := 0, 0.0
+	for v, n := range h {
+		total += n
+		sum += float64(v) * float64(n)
+	}
+	if total == 0 {
+		return 0
+	}
+	return sum / float64(total)
+}
+
+// Percentile returns the smallest value that at least p percent of the pixels
+// are lower than or equal to.
+func (h Histogram) Percentile(p float64) int {
+	total := h.Total()
+	if total == 0 {
+		return 0
+	}
+	target := math.Max(p, 0) / 100 * float64(total)
+	count := 0
+	for v, n := range h {
+		count += n
+		if n > 0 && float64(count) >= target {
+			return v
+		}
+	}
+	return h.Max()
+}
+
+// Median returns the middle value.
+func (h Histogram) Median() int {
+	return h.Percentile(50)
+}
+
+// StdDev returns the standard deviation of the values.
+func (h Histogram) StdDev() float64 {
+	total := h.Total()
+	if total == 0 {
+		return 0
+	}
+	mean := h.Mean()
+	sum := 0.0
+	for v, n := range h {
+		d := float64(v) - mean
+		sum += d * d * float64(n)
+	}
+	return math.Sqrt(sum / float64(total))
+}
+
+// Entropy returns the Shannon entropy of the values in bits per pixel.
+func (h Histogram) Entropy() float64 {
+	total := h.Total()
+	if total == 0 {
+		return 0
+	}
+	entropy := 0.0
+	for _, n := range h {
+		if n > 0 {
+			p := float64(n) / float64(total)
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
@@ -0,0 +1,264 @@
+package Netpbm
+
+// bitmap holds the pixels of a PBM image packed 64 to a word, the pixel at x
+// being bit x%64 of word x/64 of its row. Bits past the width are always 0.
+type bitmap struct {
+	rows          [][]uint64
+	width, height int
+}
+
+// newBitmap returns an empty bitmap of the given size.
+func newBitmap(width, height int) bitmap {
+	rows := make([][]uint64, height)
+	for y := range rows {
+		rows[y] = make([]uint64, (width+63)/64)
+	}
+	return bitmap{rows, width, height}
+}
+
+// bitmap packs the pixels of the PBM image.
+func (pbm *PBM) bitmap() bitmap {
+	b := newBitmap(pbm.width, pbm.height)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if pbm.data[y][x] {
+				b.rows[y][x/64] |= 1 << uint(x%64)
+			}
+		}
+	}
+	return b
+}
+
+// setBitmap unpacks the bitmap into the PBM image.
+func (pbm *PBM) setBitmap(b bitmap) {
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			pbm.data[y][x] = b.rows[y][x/64]>>uint(x%64)&1 != 0
+		}
+	}
+}
+
+// lastMask returns the bits of the last word of a row that hold pixels.
+func (b bitmap) lastMask() uint64 {
+	if b.width%64 == 0 {
+		return ^uint64(0)
+	}
+	return 1<<uint(b.width%64) - 1
+}
+
+// word returns the i-th word of the row y, as if pixels outside of the
+// bitmap were set when fill is true and clear otherwise.
+func (b bitmap) word(y, i int, fill bool) uint64 {
+	var outside uint64
+	if fill {
+		outside = ^uint64(0)
+	}
+	words := (b.width + 63) / 64
+	if y < 0 || y >= b.height || i < 0 || i >= words {
+		return outside
+	}
+	w := b.rows[y][i]
+	if i == words-1 {
+		w |= outside &^ b.lastMask()
+	}
+	return w
+}
+
+// shifted returns the i-th word of row y moved so that bit x holds the pixel
+// at x+dx, reading outside pixels according to fill.
+func (b bitmap) shifted(y, i, dx int, fill bool) uint64 {
+	if dx >= 0 {
+		q, r := dx/64, uint(dx%64)
+		w := b.word(y, i+q, fill) >> r
+		if r != 0 {
+			w |= b.word(y, i+q+1, fill) << (64 - r)
+		}
+		return w
+	}
+	q, r := -dx/64, uint(-dx%64)
+	w := b.word(y, i-q, fill) << r
+	if r != 0 {
+		w |= b.word(y, i-q-1, fill) >> (64 - r)
+	}
+	return w
+}
+
+// elementOffsets returns the positions of the set pixels of the structuring
+// element relative to its center.
+func elementOffsets(se *PBM) []Point {
+	var points []Point
+	for y := 0; y < se.height; y++ {
+		for x := 0; x < se.width; x++ {
+			if se.data[y][x] {
+				points = append(points, Point{x - se.width/2, y - se.height/2})
+			}
+		}
+	}
+	return points
+}
+
+// erode keeps the pixels where every set pixel of the structuring element
+// lands on a set pixel. Pixels outside of the bitmap count as set when fill
+// is true, so that shapes touching the border do not shrink from it.
+func (b bitmap) erode(se *PBM, fill bool) bitmap {
+	points := elementOffsets(se)
+	out := newBitmap(b.width, b.height)
+	parallelRows(b.height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for i := range out.rows[y] {
+				w := ^uint64(0)
+				for _, p := range points {
+					w &= b.shifted(y+p.Y, i, p.X, fill)
+				}
+				out.rows[y][i] = w
+			}
+			if n := len(out.rows[y]); n > 0 {
+				out.rows[y][n-1] &= b.lastMask()
+			}
+		}
+	})
+	return out
+}
+
+// dilate sets the pixels reached by the structuring element when its center
+// is placed on any set pixel.
+func (b bitmap) dilate(se *PBM) bitmap {
+	points := elementOffsets(se)
+	out := newBitmap(b.width, b.height)
+	parallelRows(b.height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for i := range out.rows[y] {
+				var w uint64
+				for _, p := range points {
+					w |= b.shifted(y-p.Y, i, -p.X, false)
+				}
+				out.rows[y][i] = w
+			}
+			if n := len(out.rows[y]); n > 0 {
+				out.rows[y][n-1] &= b.lastMask()
+			}
+		}
+	})
+	return out
+}
+
+// combine returns, word by word, f applied to the two bitmaps.
+func (b bitmap) combine(other bitmap, f func(a, b uint64) uint64) bitmap {
+	out := newBitmap(b.width, b.height)
+	for y := range out.rows {
+		for i := range out.rows[y] {
+			out.rows[y][i] = f(b.rows[y][i], other.rows[y][i])
+		}
+	}
+	return out
+}
+
+// not returns the complement of the bitmap.
+func (b bitmap) not() bitmap {
+	return b.combine(b, func(a, _ uint64) uint64 { return ^a }).mask()
+}
+
+// mask clears the bits past the width.
+func (b bitmap) mask() bitmap {
+	for y := range b.rows {
+		if n := len(b.rows[y]); n > 0 {
+			b.rows[y][n-1] &= b.lastMask()
+		}
+	}
+	return b
+}
+
+// SquareElement returns a square structuring element of side 2*radius+1.
+func SquareElement(radius int) *PBM {
+	return shapeElement(radius, WindowSquare)
+}
+
+// DiskElement returns a disk-shaped structuring element of the given radius.
+func DiskElement(radius int) *PBM {
+	return shapeElement(radius, WindowCircle)
+}
+
+// CrossElement returns a plus-shaped structuring element whose arms are
+// radius pixels long.
+func CrossElement(radius int) *PBM {
+	se := SquareElement(radius)
+	for y := range se.data {
+		for x := range se.data[y] {
+			se.data[y][x] = x == radius || y == radius
+		}
+	}
+	return se
+}
+
+// shapeElement returns a structuring element covering the window shape.
+func shapeElement(radius int, shape WindowShape) *PBM {
+	radius = max(radius, 0)
+	size := 2*radius + 1
+	se := &PBM{data: make([][]bool, size), width: size, height: size, magicNumber: "P1"}
+	for y, w := range shape.halfWidths(radius) {
+		se.data[y] = make([]bool, size)
+		for x := radius - w; x <= radius+w; x++ {
+			se.data[y][x] = true
+		}
+	}
+	return se
+}
+
+// Erode shrinks the black shapes of the PBM image, keeping only the pixels
+// where the structuring element fits entirely inside them. The structuring
+// element is a PBM image whose black pixels form the shape, its center being
+// at (width/2, height/2). Pixels outside of the image do not erode shapes.
+func (pbm *PBM) Erode(se *PBM) {
+	pbm.setBitmap(pbm.bitmap().erode(se, true))
+}
+
+// Dilate grows the black shapes of the PBM image by the structuring element.
+func (pbm *PBM) Dilate(se *PBM) {
+	pbm.setBitmap(pbm.bitmap().dilate(se))
+}
+
+// Open erodes then dilates the PBM image, which removes the details smaller
+// than the structuring element.
+func (pbm *PBM) Open(se *PBM) {
+	pbm.setBitmap(pbm.bitmap().erode(se, true).dilate(se))
+}
+
+// Close dilates then erodes the PBM image, which fills the holes and gaps
+// smaller than the structuring element.
+func (pbm *PBM) Close(se *PBM) {
+	pbm.setBitmap(pbm.bitmap().dilate(se).erode(se, true))
+}
+
+// HitOrMiss keeps the black pixels of the PBM image where hit fits the black
+// pixels and miss fits the white ones around it, which finds a given
+// pattern. Both structuring elements are centered on the pixel tested, and
+// pixels outside of the image count as white.
+func (pbm *PBM) HitOrMiss(hit, miss *PBM) {
+	b := pbm.bitmap()
+	hits := b.erode(hit, false)
+	misses := b.not().erode(miss, true)
+	pbm.setBitmap(hits.combine(misses, func(a, b uint64) uint64 { return a & b }))
+}
+
+// TopHat keeps the black details of the PBM image that opening with the
+// structuring element removes.
+func (pbm *PBM) TopHat(se *PBM) {
+	b := pbm.bitmap()
+	opened := b.erode(se, true).dilate(se)
+	pbm.setBitmap(b.combine(opened, func(a, b uint64) uint64 { return a &^ b }))
+}
+
+// BlackTopHat keeps the white holes and gaps of the PBM image that closing
+// with the structuring element fills.
+func (pbm *PBM) BlackTopHat(se *PBM) {
+	b := pbm.bitmap()
+	closed := b.dilate(se).erode(se, true)
+	pbm.setBitmap(closed.combine(b, func(a, b uint64) uint64 { return a &^ b }))
+}
+
+// MorphologicalGradient keeps the outline of the black shapes of the PBM
+// image, the pixels that dilation adds or erosion removes.
+func (pbm *PBM) MorphologicalGradient(se *PBM) {
+	b := pbm.bitmap()
+	pbm.setBitmap(b.dilate(se).combine(b.erode(se, true), func(a, b uint64) uint64 { return a &^ b }))
+}
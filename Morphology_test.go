@@ -0,0 +1,138 @@
+package Netpbm
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// testPBM returns a PBM image of the given size with random pixels.
+func testPBM(width, height int, seed int64) *PBM {
+	rng := rand.New(rand.NewSource(seed))
+	pbm := &PBM{data: make([][]bool, height), width: width, height: height, magicNumber: "P1"}
+	for y := range pbm.data {
+		pbm.data[y] = make([]bool, width)
+		for x := range pbm.data[y] {
+			pbm.data[y][x] = rng.Intn(4) != 0
+		}
+	}
+	return pbm
+}
+
+// naiveMorph erodes or dilates the PBM image pixel by pixel, reading pixels
+// outside of the image as fill.
+func naiveMorph(pbm, se *PBM, erode, fill bool) [][]bool {
+	at := func(x, y int) bool {
+		if x < 0 || y < 0 || x >= pbm.width || y >= pbm.height {
+			return fill
+		}
+		return pbm.data[y][x]
+	}
+	out := make([][]bool, pbm.height)
+	for y := range out {
+		out[y] = make([]bool, pbm.width)
+		for x := range out[y] {
+			v := erode
+			for _, p := range elementOffsets(se) {
+				if erode {
+					v = v && at(x+p.X, y+p.Y)
+				} else {
+					v = v || at(x-p.X, y-p.Y)
+				}
+			}
+			out[y][x] = v
+		}
+	}
+	return out
+}
+
+// lineElement returns a one row structuring element of the given width with
+// only the listed columns set.
+func lineElement(width int, columns ...int) *PBM {
+	se := &PBM{data: [][]bool{make([]bool, width)}, width: width, height: 1, magicNumber: "P1"}
+	for _, x := range columns {
+		se.data[0][x] = true
+	}
+	return se
+}
+
+func TestBitmapWordBoundaries(t *testing.T) {
+	elements := []struct {
+		name string
+		se   *PBM
+	}{
+		{"square", SquareElement(1)},
+		{"disk", DiskElement(2)},
+		{"far right", lineElement(131, 130)},
+		{"far left", lineElement(131, 0)},
+		{"both sides", lineElement(129, 0, 64, 128)},
+	}
+	for _, width := range []int{1, 63, 64, 65, 128, 130} {
+		img := testPBM(width, 5, int64(width))
+		for _, e := range elements {
+			tests := []struct {
+				op    string
+				got   func(b bitmap) bitmap
+				erode bool
+				fill  bool
+			}{
+				{"erode fill", func(b bitmap) bitmap { return b.erode(e.se, true) }, true, true},
+				{"erode", func(b bitmap) bitmap { return b.erode(e.se, false) }, true, false},
+				{"dilate", func(b bitmap) bitmap { return b.dilate(e.se) }, false, false},
+			}
+			for _, tt := range tests {
+				got := &PBM{data: make([][]bool, img.height), width: img.width, height: img.height}
+				for y := range got.data {
+					got.data[y] = make([]bool, img.width)
+				}
+				got.setBitmap(tt.got(img.bitmap()))
+				want := naiveMorph(img, e.se, tt.erode, tt.fill)
+				for y := range want {
+					for x := range want[y] {
+						if got.data[y][x] != want[y][x] {
+							t.Fatalf("width %d, %s, %s: pixel (%d, %d) is %v, want %v", width, e.name, tt.op, x, y, got.data[y][x], want[y][x])
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestHitOrMissFill(t *testing.T) {
+	ring := SquareElement(1)
+	ring.data[1][1] = false
+	tests := []struct {
+		name      string
+		width     int
+		black     []Point
+		hit, miss *PBM
+		want      []Point
+	}{
+		{"isolated point in a corner", 3, []Point{{0, 0}}, lineElement(1, 0), ring, []Point{{0, 0}}},
+		{"isolated point past a word", 66, []Point{{65, 2}, {10, 1}}, lineElement(1, 0), ring, []Point{{65, 2}, {10, 1}}},
+		{"neighbours are not isolated", 66, []Point{{63, 1}, {64, 1}}, lineElement(1, 0), ring, nil},
+		{"hit past the border misses", 66, []Point{{65, 1}}, lineElement(3, 2), lineElement(1), []Point{{64, 1}}},
+		{"right end of a run", 66, []Point{{62, 1}, {63, 1}, {64, 1}, {65, 1}}, lineElement(3, 0, 1), lineElement(3, 2), []Point{{65, 1}}},
+	}
+	for _, tt := range tests {
+		pbm := &PBM{data: make([][]bool, 3), width: tt.width, height: 3, magicNumber: "P1"}
+		for y := range pbm.data {
+			pbm.data[y] = make([]bool, tt.width)
+		}
+		for _, p := range tt.black {
+			pbm.data[p.Y][p.X] = true
+		}
+		pbm.HitOrMiss(tt.hit, tt.miss)
+		want := make(map[Point]bool)
+		for _, p := range tt.want {
+			want[p] = true
+		}
+		for y := range pbm.data {
+			for x := range pbm.data[y] {
+				if pbm.data[y][x] != want[Point{x, y}] {
+					t.Errorf("%s: pixel (%d, %d) is %v, want %v", tt.name, x, y, pbm.data[y][x], want[Point{x, y}])
+				}
+			}
+		}
+	}
+}
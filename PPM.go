@@ -259,54 +259,73 @@ func (ppm *PPM) Rotate90CW() {
 
 // ToPGM converts the PPM image to PGM.
 func (ppm *PPM) ToPGM() *PGM {
-	// Same idea as ppm.ToPBM
-	pgm := &PGM{}
-	pgm.magicNumber = "P2"
-	pgm.height = ppm.height
-	pgm.width = ppm.width
-
-	// Ensure that ppm.Max is within the valid range for uint8
-	if ppm.max > math.MaxUint8 {
-		pgm.max = math.MaxUint8
-	} else {
-		pgm.max = uint8(ppm.max)
-	}
+	// Use the Rec. 601 luma like netpbm's ppmtopgm, see ToPGMWith for other methods
+	return ppm.ToPGMWith(GrayRec601)
+}
+
+// ToPBM converts the PPM image to PBM.
+func (ppm *PPM) ToPBM() *PBM {
+	// Pixels whose luminance is lower than half of the maximum value become black,
+	// see ToPBMDither for dithered conversions
+	return ppm.ToPBMDither(DitherOptions{Method: DitherThreshold})
+}
 
-	for y, _ := range ppm.data {
-		pgm.data = append(pgm.data, []uint8{})
-		for x, _ := range ppm.data[y] {
-			r, g, b := ppm.data[y][x].R, ppm.data[y][x].G, ppm.data[y][x].B
-			// Calculate the amount of gray the pixel should have
-			// It is just the average of the 3 RGB colors
-			grayValue := uint8((int(r) + int(g) + int(b)) / 3)
-			pgm.data[y] = append(pgm.data[y], grayValue)
+// Split separates the PPM image into three PGM images holding its red, green
+// and blue channels, each with the max value of the PPM image.
+func (ppm *PPM) Split() (r, g, b *PGM) {
+	planes := [3]*PGM{}
+	for i := range planes {
+		planes[i] = &PGM{
+			data:        make([][]uint8, ppm.height),
+			width:       ppm.width,
+			height:      ppm.height,
+			magicNumber: "P2",
+			max:         ppm.max,
 		}
 	}
-	return pgm
+	for y := 0; y < ppm.height; y++ {
+		for i := range planes {
+			planes[i].data[y] = make([]uint8, ppm.width)
+		}
+		for x := 0; x < ppm.width; x++ {
+			pixel := ppm.data[y][x]
+			planes[0].data[y][x] = pixel.R
+			planes[1].data[y][x] = pixel.G
+			planes[2].data[y][x] = pixel.B
+		}
+	}
+	return planes[0], planes[1], planes[2]
 }
 
-// ToPBM converts the PPM image to PBM.
-func (ppm *PPM) ToPBM() *PBM {
-	const threshold = 2
-
-	pbm := &PBM{}
-	pbm.magicNumber = "P1"
-	pbm.height = ppm.height
-	pbm.width = ppm.width
-
-	for y, _ := range ppm.data {
-		pbm.data = append(pbm.data, []bool{})
-		for x, _ := range ppm.data[y] {
-			r, g, b := ppm.data[y][x].R, ppm.data[y][x].G, ppm.data[y][x].B
-			// Calculate whether the pixel should be black or white
-			// If the average of the 3 colors is lower than half of the maximum value, then consider it white
-			// If maxValue is 100 and the average is 49, it would be black
-			maxValue := uint8(ppm.max)
-			isBlack := (uint8((int(r)+int(g)+int(b))/3) < maxValue/uint8(threshold))
-			pbm.data[y] = append(pbm.data[y], isBlack)
+// MergePPM builds a PPM image from three PGM images used as its red, green
+// and blue channels. The images must have the same size; the result takes the
+// largest of their max values and the other channels are rescaled to it.
+func MergePPM(r, g, b *PGM) (*PPM, error) {
+	if r == nil || g == nil || b == nil {
+		return nil, fmt.Errorf("missing channel")
+	}
+	if r.width != g.width || r.width != b.width || r.height != g.height || r.height != b.height {
+		return nil, fmt.Errorf("channel sizes differ: %dx%d, %dx%d and %dx%d", r.width, r.height, g.width, g.height, b.width, b.height)
+	}
+
+	ppm := &PPM{
+		data:        make([][]Pixel, r.height),
+		width:       r.width,
+		height:      r.height,
+		magicNumber: "P3",
+		max:         max(r.max, g.max, b.max),
+	}
+	for y := 0; y < ppm.height; y++ {
+		ppm.data[y] = make([]Pixel, ppm.width)
+		for x := 0; x < ppm.width; x++ {
+			ppm.data[y][x] = Pixel{
+				R: rescale(r.data[y][x], r.max, ppm.max),
+				G: rescale(g.data[y][x], g.max, ppm.max),
+				B: rescale(b.data[y][x], b.max, ppm.max),
+			}
 		}
 	}
-	return pbm
+	return ppm, nil
 }
 
 type Point struct {
@@ -0,0 +1,62 @@
+package Netpbm
+
+// padData returns a copy of data grown by the given number of pixels on each
+// side, the new pixels being filled according to the edge mode.
+func padData[T any](data [][]T, width, height, top, right, bottom, left int, edge EdgeMode, fill T) [][]T {
+	top, right, bottom, left = max(top, 0), max(right, 0), max(bottom, 0), max(left, 0)
+	newWidth, newHeight := width+left+right, height+top+bottom
+
+	padded := make([][]T, newHeight)
+	for y := 0; y < newHeight; y++ {
+		padded[y] = make([]T, newWidth)
+		sy, okY := edgeIndex(y-top, height, edge)
+		for x := 0; x < newWidth; x++ {
+			sx, okX := edgeIndex(x-left, width, edge)
+			if okX && okY {
+				padded[y][x] = data[sy][sx]
+			} else {
+				padded[y][x] = fill
+			}
+		}
+	}
+	return padded
+}
+
+// Pad grows the PBM image by the given number of pixels on each side, like
+// pnmpad. The new pixels are filled according to the edge mode, EdgeConstant using fill.
+func (pbm *PBM) Pad(top, right, bottom, left int, edge EdgeMode, fill bool) {
+	pbm.data = padData(pbm.data, pbm.width, pbm.height, top, right, bottom, left, edge, fill)
+	pbm.height = len(pbm.data)
+	pbm.width += max(left, 0) + max(right, 0)
+}
+
+// Pad grows the PGM image by the given number of pixels on each side, like
+// pnmpad. The new pixels are filled according to the edge mode, EdgeConstant using fill.
+func (pgm *PGM) Pad(top, right, bottom, left int, edge EdgeMode, fill uint8) {
+	pgm.data = padData(pgm.data, pgm.width, pgm.height, top, right, bottom, left, edge, fill)
+	pgm.height = len(pgm.data)
+	pgm.width += max(left, 0) + max(right, 0)
+}
+
+// Pad grows the PPM image by the given number of pixels on each side, like
+// pnmpad. The new pixels are filled according to the edge mode, EdgeConstant using fill.
+func (ppm *PPM) Pad(top, right, bottom, left int, edge EdgeMode, fill Pixel) {
+	ppm.data = padData(ppm.data, ppm.width, ppm.height, top, right, bottom, left, edge, fill)
+	ppm.height = len(ppm.data)
+	ppm.width += max(left, 0) + max(right, 0)
+}
+
+// AddBorder surrounds the PBM image with a border of the given size and color, like pamaddborder.
+func (pbm *PBM) AddBorder(size int, color bool) {
+	pbm.Pad(size, size, size, size, EdgeConstant, color)
+}
+
+// AddBorder surrounds the PGM image with a border of the given size and value, like pamaddborder.
+func (pgm *PGM) AddBorder(size int, value uint8) {
+	pgm.Pad(size, size, size, size, EdgeConstant, value)
+}
+
+// AddBorder surrounds the PPM image with a border of the given size and color, like pamaddborder.
+func (ppm *PPM) AddBorder(size int, color Pixel) {
+	ppm.Pad(size, size, size, size, EdgeConstant, color)
+}
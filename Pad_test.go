@@ -0,0 +1,74 @@
+package Netpbm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPadEdgeModes(t *testing.T) {
+	tests := []struct {
+		name string
+		edge EdgeMode
+		want []uint8
+	}{
+		{"constant", EdgeConstant, []uint8{9, 9, 9, 9, 9, 1, 2, 3, 4, 9, 9, 9, 9, 9}},
+		{"clamp", EdgeClamp, []uint8{1, 1, 1, 1, 1, 1, 2, 3, 4, 4, 4, 4, 4, 4}},
+		{"wrap", EdgeWrap, []uint8{4, 1, 2, 3, 4, 1, 2, 3, 4, 1, 2, 3, 4, 1}},
+		{"reflect", EdgeReflect, []uint8{2, 3, 4, 3, 2, 1, 2, 3, 4, 3, 2, 1, 2, 3}},
+	}
+	for _, tt := range tests {
+		pgm := &PGM{data: [][]uint8{{1, 2, 3, 4}}, width: 4, height: 1, magicNumber: "P2", max: 255}
+		pgm.Pad(0, 5, 0, 5, tt.edge, 9)
+		if pgm.width != 14 || pgm.height != 1 || !reflect.DeepEqual(pgm.data[0], tt.want) {
+			t.Errorf("%s: got %dx%d %v, want %v", tt.name, pgm.width, pgm.height, pgm.data, tt.want)
+		}
+
+		// The same modes apply to columns
+		column := &PGM{data: [][]uint8{{1}, {2}, {3}, {4}}, width: 1, height: 4, magicNumber: "P2", max: 255}
+		column.Pad(5, 0, 5, 0, tt.edge, 9)
+		got := make([]uint8, column.height)
+		for y := range got {
+			got[y] = column.data[y][0]
+		}
+		if column.width != 1 || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got column %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPadSizes(t *testing.T) {
+	tests := []struct {
+		name                     string
+		top, right, bottom, left int
+		width, height            int
+	}{
+		{"none", 0, 0, 0, 0, 3, 2},
+		{"all sides", 1, 2, 3, 4, 9, 6},
+		{"negative counts as zero", -1, 2, -3, -4, 5, 2},
+	}
+	for _, tt := range tests {
+		pgm := randomPGM(3, 2, 256, 1)
+		pgm.Pad(tt.top, tt.right, tt.bottom, tt.left, EdgeConstant, 0)
+		ppm := randomPPM(3, 2, 1)
+		ppm.Pad(tt.top, tt.right, tt.bottom, tt.left, EdgeConstant, Pixel{})
+		pbm := &PBM{data: [][]bool{{true, false, true}, {false, true, false}}, width: 3, height: 2, magicNumber: "P1"}
+		pbm.Pad(tt.top, tt.right, tt.bottom, tt.left, EdgeConstant, false)
+		for _, got := range [][2]int{{pgm.width, pgm.height}, {ppm.width, ppm.height}, {pbm.width, pbm.height}} {
+			if got != [2]int{tt.width, tt.height} {
+				t.Errorf("%s: got %dx%d, want %dx%d", tt.name, got[0], got[1], tt.width, tt.height)
+			}
+		}
+		if len(pgm.data) != tt.height || len(pgm.data[0]) != tt.width {
+			t.Errorf("%s: PGM data is %dx%d, want %dx%d", tt.name, len(pgm.data[0]), len(pgm.data), tt.width, tt.height)
+		}
+	}
+}
+
+func TestAddBorder(t *testing.T) {
+	pgm := &PGM{data: [][]uint8{{5}}, width: 1, height: 1, magicNumber: "P2", max: 255}
+	pgm.AddBorder(1, 0)
+	want := [][]uint8{{0, 0, 0}, {0, 5, 0}, {0, 0, 0}}
+	if !reflect.DeepEqual(pgm.data, want) {
+		t.Errorf("got %v, want %v", pgm.data, want)
+	}
+}
@@ -0,0 +1,118 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// pasteMask returns a function giving the opacity in [0, 1] of each source
+// pixel. A nil mask, including a nil *PBM or *PGM, makes every pixel opaque.
+// A PBM mask copies the pixels where it is black, and a PGM mask blends by
+// value, its max value being opaque.
+func pasteMask(mask Image, width, height int) (func(x, y int) float64, error) {
+	switch m := mask.(type) {
+	case *PBM:
+		if m == nil {
+			mask = nil
+		}
+	case *PGM:
+		if m == nil {
+			mask = nil
+		}
+	}
+	if mask == nil {
+		return func(x, y int) float64 { return 1 }, nil
+	}
+	maskWidth, maskHeight := mask.Size()
+	if maskWidth != width || maskHeight != height {
+		return nil, fmt.Errorf("mask is %dx%d but the image is %dx%d", maskWidth, maskHeight, width, height)
+	}
+	switch mask := mask.(type) {
+	case *PBM:
+		return func(x, y int) float64 {
+			if mask.data[y][x] {
+				return 1
+			}
+			return 0
+		}, nil
+	case *PGM:
+		return func(x, y int) float64 {
+			if mask.max == 0 {
+				return 0
+			}
+			return float64(mask.data[y][x]) / float64(mask.max)
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported mask type %T", mask)
+}
+
+// blend mixes the samples a and b, alpha being the weight of b.
+func blend(a, b uint8, alpha float64) uint8 {
+	return uint8(math.Round(float64(a)*(1-alpha) + float64(b)*alpha))
+}
+
+// Paste copies src onto the PGM image with its top left corner at the given
+// point, like pnmpaste. Parts of src outside of the image are clipped.
+// The optional mask, nil or a *PBM or *PGM of the same size as src,
+// selects or weights the pasted pixels. src is rescaled to the max value of
+// the image.
+func (pgm *PGM) Paste(src *PGM, at Point, mask Image) error {
+	weight, err := pasteMask(mask, src.width, src.height)
+	if err != nil {
+		return err
+	}
+	for y := 0; y < src.height; y++ {
+		dy := at.Y + y
+		if dy < 0 || dy >= pgm.height {
+			continue
+		}
+		for x := 0; x < src.width; x++ {
+			dx := at.X + x
+			if dx < 0 || dx >= pgm.width {
+				continue
+			}
+			alpha := weight(x, y)
+			if alpha <= 0 {
+				continue
+			}
+			value := rescale(src.data[y][x], src.max, pgm.max)
+			pgm.data[dy][dx] = blend(pgm.data[dy][dx], value, alpha)
+		}
+	}
+	return nil
+}
+
+// Paste copies src onto the PPM image with its top left corner at the given
+// point, like pnmpaste. Parts of src outside of the image are clipped.
+// The optional mask, nil or a *PBM or *PGM of the same size as src,
+// selects or weights the pasted pixels. src is rescaled to the max value of
+// the image.
+func (ppm *PPM) Paste(src *PPM, at Point, mask Image) error {
+	weight, err := pasteMask(mask, src.width, src.height)
+	if err != nil {
+		return err
+	}
+	for y := 0; y < src.height; y++ {
+		dy := at.Y + y
+		if dy < 0 || dy >= ppm.height {
+			continue
+		}
+		for x := 0; x < src.width; x++ {
+			dx := at.X + x
+			if dx < 0 || dx >= ppm.width {
+				continue
+			}
+			alpha := weight(x, y)
+			if alpha <= 0 {
+				continue
+			}
+			p, q := ppm.data[dy][dx], src.data[y][x]
+			ppm.data[dy][dx] = Pixel{
+				R: blend(p.R, rescale(q.R, src.max, ppm.max), alpha),
+				G: blend(p.G, rescale(q.G, src.max, ppm.max), alpha),
+				B: blend(p.B, rescale(q.B, src.max, ppm.max), alpha),
+			}
+		}
+	}
+	return nil
+}
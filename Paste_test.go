@@ -0,0 +1,94 @@
+package Netpbm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPastePGM(t *testing.T) {
+	src := &PGM{data: [][]uint8{{1, 2}, {3, 4}}, width: 2, height: 2, magicNumber: "P2", max: 255}
+	var nilPBM *PBM
+	var nilPGM *PGM
+	tests := []struct {
+		name string
+		at   Point
+		mask Image
+		want [][]uint8
+	}{
+		{"inside", Point{1, 0}, nil, [][]uint8{{0, 1, 2}, {0, 3, 4}, {0, 0, 0}}},
+		{"clipped top left", Point{-1, -1}, nil, [][]uint8{{4, 0, 0}, {0, 0, 0}, {0, 0, 0}}},
+		{"clipped bottom right", Point{2, 2}, nil, [][]uint8{{0, 0, 0}, {0, 0, 0}, {0, 0, 1}}},
+		{"outside", Point{3, 0}, nil, [][]uint8{{0, 0, 0}, {0, 0, 0}, {0, 0, 0}}},
+		{"nil PBM mask", Point{0, 0}, nilPBM, [][]uint8{{1, 2, 0}, {3, 4, 0}, {0, 0, 0}}},
+		{"nil PGM mask", Point{0, 0}, nilPGM, [][]uint8{{1, 2, 0}, {3, 4, 0}, {0, 0, 0}}},
+		{
+			"PBM mask",
+			Point{0, 0},
+			&PBM{data: [][]bool{{true, false}, {false, true}}, width: 2, height: 2, magicNumber: "P1"},
+			[][]uint8{{1, 0, 0}, {0, 4, 0}, {0, 0, 0}},
+		},
+	}
+	for _, tt := range tests {
+		dst := flatPGM(3, 3, 0, 255)
+		if err := dst.Paste(src, tt.at, tt.mask); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(dst.data, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, dst.data, tt.want)
+		}
+	}
+}
+
+func TestPasteBlend(t *testing.T) {
+	dst := flatPGM(3, 1, 100, 255)
+	src := flatPGM(3, 1, 200, 255)
+	mask := &PGM{data: [][]uint8{{0, 2, 4}}, width: 3, height: 1, magicNumber: "P2", max: 4}
+	if err := dst.Paste(src, Point{}, mask); err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint8{100, 150, 200}; !reflect.DeepEqual(dst.data[0], want) {
+		t.Errorf("got %v, want %v", dst.data[0], want)
+	}
+
+	ppm := &PPM{data: [][]Pixel{{{0, 0, 0}}}, width: 1, height: 1, magicNumber: "P3", max: 255}
+	red := &PPM{data: [][]Pixel{{{200, 100, 0}}}, width: 1, height: 1, magicNumber: "P3", max: 255}
+	half := &PGM{data: [][]uint8{{1}}, width: 1, height: 1, magicNumber: "P2", max: 2}
+	if err := ppm.Paste(red, Point{}, half); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Pixel{100, 50, 0}); ppm.data[0][0] != want {
+		t.Errorf("got %v, want %v", ppm.data[0][0], want)
+	}
+}
+
+func TestPasteRescale(t *testing.T) {
+	dst := flatPGM(2, 1, 0, 255)
+	src := &PGM{data: [][]uint8{{15, 5}}, width: 2, height: 1, magicNumber: "P2", max: 15}
+	if err := dst.Paste(src, Point{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint8{255, 85}; !reflect.DeepEqual(dst.data[0], want) {
+		t.Errorf("got %v, want %v", dst.data[0], want)
+	}
+}
+
+func TestPasteMaskErrors(t *testing.T) {
+	src := flatPGM(2, 2, 9, 255)
+	tests := []struct {
+		name string
+		mask Image
+	}{
+		{"mask too small", flatPGM(1, 2, 255, 255)},
+		{"PPM mask", &PPM{data: [][]Pixel{{{}, {}}, {{}, {}}}, width: 2, height: 2, magicNumber: "P3", max: 255}},
+	}
+	for _, tt := range tests {
+		dst := flatPGM(2, 2, 0, 255)
+		if err := dst.Paste(src, Point{}, tt.mask); err == nil {
+			t.Errorf("%s: got no error", tt.name)
+		}
+		if !samePGM(dst, flatPGM(2, 2, 0, 255)) {
+			t.Errorf("%s: image changed on error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package Netpbm
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// QuantizeMethod selects how Quantize chooses the palette.
+type QuantizeMethod int
+
+const (
+	// QuantizeMedianCut splits the color cube into boxes holding the same
+	// number of pixels, like pnmquant.
+	QuantizeMedianCut QuantizeMethod = iota
+	// QuantizeOctree merges the leaves of an octree of the colors until few
+	// enough remain.
+	QuantizeOctree
+	// QuantizeKMeans refines the palette with k-means clustering, starting
+	// from k-means++ seeds drawn with Seed.
+	QuantizeKMeans
+)
+
+// QuantizeOptions configures Quantize.
+type QuantizeOptions struct {
+	// Method is the palette selection algorithm.
+	Method QuantizeMethod
+	// Colors is the largest number of colors in the palette, 256 by default.
+	Colors int
+	// Seed makes k-means results reproducible.
+	Seed int64
+	// Dither remaps the image with Floyd-Steinberg error diffusion.
+	Dither bool
+}
+
+// colorCount is a distinct color of an image and the number of pixels using it.
+type colorCount struct {
+	color Pixel
+	count int
+}
+
+// distinctColors returns the colors of the PPM image sorted by value, so that
+// the quantizers do not depend on map iteration order.
+func (ppm *PPM) distinctColors() []colorCount {
+	counts := make(map[Pixel]int)
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			counts[ppm.data[y][x]]++
+		}
+	}
+	colors := make([]colorCount, 0, len(counts))
+	for c, n := range counts {
+		colors = append(colors, colorCount{c, n})
+	}
+	sort.Slice(colors, func(i, j int) bool {
+		a, b := colors[i].color, colors[j].color
+		if a.R != b.R {
+			return a.R < b.R
+		}
+		if a.G != b.G {
+			return a.G < b.G
+		}
+		return a.B < b.B
+	})
+	return colors
+}
+
+// channelOf returns the red, green or blue component of the pixel.
+func channelOf(p Pixel, i int) uint8 {
+	switch i {
+	case 0:
+		return p.R
+	case 1:
+		return p.G
+	}
+	return p.B
+}
+
+// averageColor returns the average of the colors weighted by their count.
+func averageColor(colors []colorCount) Pixel {
+	var r, g, b, n float64
+	for _, c := range colors {
+		w := float64(c.count)
+		r += float64(c.color.R) * w
+		g += float64(c.color.G) * w
+		b += float64(c.color.B) * w
+		n += w
+	}
+	if n == 0 {
+		return Pixel{}
+	}
+	return Pixel{uint8(math.Round(r / n)), uint8(math.Round(g / n)), uint8(math.Round(b / n))}
+}
+
+// Quantize reduces the PPM image to at most opts.Colors colors. It returns the
+// palette and a new image using only colors of the palette.
+func (ppm *PPM) Quantize(opts QuantizeOptions) ([]Pixel, *PPM) {
+	n := opts.Colors
+	if n <= 0 {
+		n = 256
+	}
+	colors := ppm.distinctColors()
+
+	var palette []Pixel
+	switch {
+	case len(colors) <= n:
+		for _, c := range colors {
+			palette = append(palette, c.color)
+		}
+	case opts.Method == QuantizeOctree:
+		palette = octreePalette(colors, n)
+	case opts.Method == QuantizeKMeans:
+		palette = kMeansPalette(colors, n, opts.Seed)
+	default:
+		palette = medianCutPalette(colors, n)
+	}
+	return palette, ppm.remap(palette, opts.Dither)
+}
+
+// medianCutPalette splits the colors into n boxes, always cutting the box with
+// the widest range at the median of its longest side.
+func medianCutPalette(colors []colorCount, n int) []Pixel {
+	boxes := [][]colorCount{colors}
+	for len(boxes) < n {
+		// Find the box with the widest channel range
+		best, bestAxis, bestRange := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			for axis := 0; axis < 3; axis++ {
+				lo, hi := uint8(255), uint8(0)
+				for _, c := range box {
+					v := channelOf(c.color, axis)
+					lo, hi = min(lo, v), max(hi, v)
+				}
+				if int(hi)-int(lo) > bestRange {
+					best, bestAxis, bestRange = i, axis, int(hi)-int(lo)
+				}
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		// Cut it where half of its pixels are on each side
+		box := boxes[best]
+		sort.SliceStable(box, func(i, j int) bool {
+			return channelOf(box[i].color, bestAxis) < channelOf(box[j].color, bestAxis)
+		})
+		total := 0
+		for _, c := range box {
+			total += c.count
+		}
+		cut, count := 1, box[0].count
+		for cut < len(box)-1 && count*2 < total {
+			count += box[cut].count
+			cut++
+		}
+		boxes[best] = box[:cut:cut]
+		boxes = append(boxes, box[cut:])
+	}
+
+	palette := make([]Pixel, len(boxes))
+	for i, box := range boxes {
+		palette[i] = averageColor(box)
+	}
+	return palette
+}
+
+// octreeNode is a node of the color octree. Leaves keep the sum of the colors
+// they hold, and every node the number of pixels below it.
+type octreeNode struct {
+	children   [8]*octreeNode
+	r, g, b    float64
+	count      int
+	pixels     int
+	leaf       bool
+	childCount int
+}
+
+// octreePalette builds an 8-level octree of the colors and merges the
+// children of the deepest, least used nodes until at most n leaves remain.
+func octreePalette(colors []colorCount, n int) []Pixel {
+	const depth = 8
+	root := &octreeNode{}
+	levels := make([][]*octreeNode, depth)
+	leaves := 0
+
+	for _, c := range colors {
+		node := root
+		root.pixels += c.count
+		for level := 0; level < depth; level++ {
+			shift := 7 - level
+			i := int(c.color.R>>shift&1)<<2 | int(c.color.G>>shift&1)<<1 | int(c.color.B>>shift&1)
+			if node.children[i] == nil {
+				node.children[i] = &octreeNode{leaf: level == depth-1}
+				node.childCount++
+				if level < depth-1 {
+					levels[level+1] = append(levels[level+1], node.children[i])
+				} else {
+					leaves++
+				}
+			}
+			node = node.children[i]
+			node.pixels += c.count
+		}
+		w := float64(c.count)
+		node.r += float64(c.color.R) * w
+		node.g += float64(c.color.G) * w
+		node.b += float64(c.color.B) * w
+		node.count += c.count
+	}
+	levels[0] = []*octreeNode{root}
+
+	for level := depth - 1; level >= 0 && leaves > n; level-- {
+		nodes := levels[level]
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].pixels < nodes[j].pixels })
+		for _, node := range nodes {
+			if leaves <= n {
+				break
+			}
+			if node.leaf || node.childCount == 0 {
+				continue
+			}
+			// Fold the children into the node, which becomes a leaf
+			for i, child := range node.children {
+				if child != nil {
+					node.r += child.r
+					node.g += child.g
+					node.b += child.b
+					node.count += child.count
+					node.children[i] = nil
+				}
+			}
+			leaves -= node.childCount - 1
+			node.childCount = 0
+			node.leaf = true
+		}
+	}
+
+	var palette []Pixel
+	var collect func(node *octreeNode)
+	collect = func(node *octreeNode) {
+		if node.leaf {
+			if node.count > 0 {
+				c := float64(node.count)
+				palette = append(palette, Pixel{uint8(math.Round(node.r / c)), uint8(math.Round(node.g / c)), uint8(math.Round(node.b / c))})
+			}
+			return
+		}
+		for _, child := range node.children {
+			if child != nil {
+				collect(child)
+			}
+		}
+	}
+	collect(root)
+	return palette
+}
+
+// colorDistance returns the squared distance between two colors.
+func colorDistance(a Pixel, r, g, b float64) float64 {
+	dr, dg, db := float64(a.R)-r, float64(a.G)-g, float64(a.B)-b
+	return dr*dr + dg*dg + db*db
+}
+
+// kMeansPalette clusters the colors into n groups with k-means, seeding the
+// centers with k-means++.
+func kMeansPalette(colors []colorCount, n int, seed int64) []Pixel {
+	rng := rand.New(rand.NewSource(seed))
+	centers := make([][3]float64, 0, n)
+	pick := func(i int) {
+		c := colors[i].color
+		centers = append(centers, [3]float64{float64(c.R), float64(c.G), float64(c.B)})
+	}
+
+	// k-means++: each new center is drawn with probability proportional to
+	// the squared distance to the closest center already chosen
+	total := 0
+	for _, c := range colors {
+		total += c.count
+	}
+	target, i := rng.Intn(total), 0
+	for target >= colors[i].count {
+		target -= colors[i].count
+		i++
+	}
+	pick(i)
+	nearest := make([]float64, len(colors))
+	for len(centers) < n {
+		sum := 0.0
+		last := centers[len(centers)-1]
+		for i, c := range colors {
+			d := colorDistance(c.color, last[0], last[1], last[2])
+			if len(centers) == 1 || d < nearest[i] {
+				nearest[i] = d
+			}
+			sum += nearest[i] * float64(c.count)
+		}
+		if sum == 0 {
+			break
+		}
+		r := rng.Float64() * sum
+		next := len(colors) - 1
+		for i, c := range colors {
+			r -= nearest[i] * float64(c.count)
+			if r < 0 {
+				next = i
+				break
+			}
+		}
+		pick(next)
+	}
+
+	// Lloyd iterations
+	assignment := make([]int, len(colors))
+	for iteration := 0; iteration < 32; iteration++ {
+		changed := false
+		for i, c := range colors {
+			best, bestDistance := 0, math.Inf(1)
+			for k, center := range centers {
+				if d := colorDistance(c.color, center[0], center[1], center[2]); d < bestDistance {
+					best, bestDistance = k, d
+				}
+			}
+			if assignment[i] != best || iteration == 0 {
+				assignment[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		sums := make([][4]float64, len(centers))
+		for i, c := range colors {
+			w := float64(c.count)
+			s := &sums[assignment[i]]
+			s[0] += float64(c.color.R) * w
+			s[1] += float64(c.color.G) * w
+			s[2] += float64(c.color.B) * w
+			s[3] += w
+		}
+		for k, s := range sums {
+			if s[3] > 0 {
+				centers[k] = [3]float64{s[0] / s[3], s[1] / s[3], s[2] / s[3]}
+			}
+		}
+	}
+
+	palette := make([]Pixel, len(centers))
+	for k, c := range centers {
+		palette[k] = Pixel{uint8(math.Round(c[0])), uint8(math.Round(c[1])), uint8(math.Round(c[2]))}
+	}
+	return palette
+}
+
+// nearestColor returns the index of the palette color closest to (r, g, b).
+func nearestColor(palette []Pixel, r, g, b float64) int {
+	best, bestDistance := 0, math.Inf(1)
+	for i, p := range palette {
+		if d := colorDistance(p, r, g, b); d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return best
+}
+
+// remap returns a copy of the PPM image where every pixel is replaced by the
+// closest palette color, optionally diffusing the error Floyd-Steinberg style.
+func (ppm *PPM) remap(palette []Pixel, dither bool) *PPM {
+	out := &PPM{
+		data:        make([][]Pixel, ppm.height),
+		width:       ppm.width,
+		height:      ppm.height,
+		magicNumber: ppm.magicNumber,
+		max:         ppm.max,
+	}
+	for y := range out.data {
+		out.data[y] = make([]Pixel, ppm.width)
+	}
+	if len(palette) == 0 {
+		return out
+	}
+
+	if !dither {
+		cache := make(map[Pixel]Pixel)
+		for y := 0; y < ppm.height; y++ {
+			for x := 0; x < ppm.width; x++ {
+				p := ppm.data[y][x]
+				q, ok := cache[p]
+				if !ok {
+					q = palette[nearestColor(palette, float64(p.R), float64(p.G), float64(p.B))]
+					cache[p] = q
+				}
+				out.data[y][x] = q
+			}
+		}
+		return out
+	}
+
+	ch := ppm.channels()
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			old := [3]float64{ch[0][y][x], ch[1][y][x], ch[2][y][x]}
+			q := palette[nearestColor(palette, old[0], old[1], old[2])]
+			out.data[y][x] = q
+			for i, v := range pixelSamples(q) {
+				err := old[i] - v
+				spread := func(dx, dy int, w float64) {
+					if x+dx >= 0 && x+dx < ppm.width && y+dy < ppm.height {
+						ch[i][y+dy][x+dx] += err * w
+					}
+				}
+				spread(1, 0, 7.0/16)
+				spread(-1, 1, 3.0/16)
+				spread(0, 1, 5.0/16)
+				spread(1, 1, 1.0/16)
+			}
+		}
+	}
+	return out
+}
+
+// Remap returns a copy of the PPM image using only the colors found in the
+// palette image, like pnmremap. The palette is rescaled to the max value of
+// the image. With dither set the error is diffused Floyd-Steinberg style.
+func (ppm *PPM) Remap(palette *PPM, dither bool) *PPM {
+	colors := palette.distinctColors()
+	pixels := make([]Pixel, len(colors))
+	for i, c := range colors {
+		p := c.color
+		pixels[i] = Pixel{rescale(p.R, palette.max, ppm.max), rescale(p.G, palette.max, ppm.max), rescale(p.B, palette.max, ppm.max)}
+	}
+	return ppm.remap(pixels, dither)
+}
@@ -0,0 +1,90 @@
+package Netpbm
+
+import (
+	"math"
+	"testing"
+)
+
+// weightedMean returns the average color of the counts, rounded like the
+// octree leaves.
+func weightedMean(colors []colorCount) Pixel {
+	var r, g, b float64
+	total := 0
+	for _, c := range colors {
+		w := float64(c.count)
+		r += float64(c.color.R) * w
+		g += float64(c.color.G) * w
+		b += float64(c.color.B) * w
+		total += c.count
+	}
+	n := float64(total)
+	return Pixel{uint8(math.Round(r / n)), uint8(math.Round(g / n)), uint8(math.Round(b / n))}
+}
+
+// gridColors returns steps^3 colors spread over the whole cube, each used
+// by a different number of pixels.
+func gridColors(steps int) []colorCount {
+	var colors []colorCount
+	for r := 0; r < steps; r++ {
+		for g := 0; g < steps; g++ {
+			for b := 0; b < steps; b++ {
+				c := Pixel{uint8(r * 255 / (steps - 1)), uint8(g * 255 / (steps - 1)), uint8(b * 255 / (steps - 1))}
+				colors = append(colors, colorCount{c, 1 + len(colors)%7})
+			}
+		}
+	}
+	return colors
+}
+
+func TestOctreePalette(t *testing.T) {
+	few := []colorCount{
+		{Pixel{0, 0, 0}, 5},
+		{Pixel{255, 0, 0}, 3},
+		{Pixel{0, 128, 255}, 1},
+	}
+	siblings := []colorCount{
+		// Differ only in the last bit, so they share a parent at the
+		// deepest level and are folded first
+		{Pixel{100, 100, 100}, 1},
+		{Pixel{101, 100, 100}, 3},
+		{Pixel{255, 255, 255}, 10},
+	}
+	tests := []struct {
+		name   string
+		colors []colorCount
+		n      int
+		want   []Pixel
+	}{
+		{"few colors are kept exactly", few, 8, []Pixel{{0, 0, 0}, {0, 128, 255}, {255, 0, 0}}},
+		{"exactly enough leaves", few, 3, []Pixel{{0, 0, 0}, {0, 128, 255}, {255, 0, 0}}},
+		{"siblings fold into their weighted mean", siblings, 2, []Pixel{{101, 100, 100}, {255, 255, 255}}},
+		{"one color folds everything", few, 1, []Pixel{weightedMean(few)}},
+		{"one color from a grid", gridColors(5), 1, []Pixel{weightedMean(gridColors(5))}},
+	}
+	for _, tt := range tests {
+		got := octreePalette(tt.colors, tt.n)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestOctreePaletteSize(t *testing.T) {
+	colors := gridColors(9)
+	for _, n := range []int{1, 2, 7, 8, 9, 64, 100, 729, 1000} {
+		got := octreePalette(colors, n)
+		if len(got) == 0 || len(got) > n {
+			t.Errorf("%d colors: got a palette of %d", n, len(got))
+		}
+		if n >= len(colors) && len(got) != len(colors) {
+			t.Errorf("%d colors: got a palette of %d, want all %d", n, len(got), len(colors))
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package Netpbm
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+// naiveRank returns the p-th percentile of the window around (x, y), sorting
+// every sample of the window with the edge pixels repeated.
+func naiveRank(pgm *PGM, x, y, radius int, shape WindowShape, p float64) uint8 {
+	var window []int
+	r := float64(radius) + 0.5
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			if shape == WindowCircle && float64(dx*dx+dy*dy) > r*r {
+				continue
+			}
+			window = append(window, int(pgm.data[clampIndex(y+dy, pgm.height)][clampIndex(x+dx, pgm.width)]))
+		}
+	}
+	sort.Ints(window)
+	i := max(int(math.Ceil(p/100*float64(len(window))))-1, 0)
+	return uint8(window[i])
+}
+
+func TestRankFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		radius int
+		shape  WindowShape
+		p      float64
+		filter func(pgm *PGM, radius int, shape WindowShape)
+	}{
+		{"median square", 1, WindowSquare, 50, (*PGM).MedianFilter},
+		{"median square 3", 3, WindowSquare, 50, (*PGM).MedianFilter},
+		{"median circle", 2, WindowCircle, 50, (*PGM).MedianFilter},
+		{"median circle 4", 4, WindowCircle, 50, (*PGM).MedianFilter},
+		{"min", 2, WindowSquare, 0, (*PGM).MinFilter},
+		{"max", 2, WindowCircle, 100, (*PGM).MaxFilter},
+		{"percentile 25", 3, WindowCircle, 25, func(pgm *PGM, radius int, shape WindowShape) {
+			pgm.PercentileFilter(radius, shape, 25)
+		}},
+		{"larger than the image", 9, WindowSquare, 50, (*PGM).MedianFilter},
+	}
+	for _, tt := range tests {
+		for _, levels := range []int{4, 256} {
+			src := randomPGM(13, 8, levels, int64(levels))
+			got := copyPGM(src)
+			tt.filter(got, tt.radius, tt.shape)
+			for y := range src.data {
+				for x := range src.data[y] {
+					if want := naiveRank(src, x, y, tt.radius, tt.shape, tt.p); got.data[y][x] != want {
+						t.Fatalf("%s, %d levels: pixel (%d, %d) is %d, want %d", tt.name, levels, x, y, got.data[y][x], want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMedianFilterRemovesSpeckles(t *testing.T) {
+	pgm := flatPGM(7, 7, 100, 255)
+	pgm.data[1][1], pgm.data[3][4], pgm.data[6][6] = 0, 255, 0
+	pgm.MedianFilter(1, WindowSquare)
+	if !samePGM(pgm, flatPGM(7, 7, 100, 255)) {
+		t.Errorf("speckles remain: %v", pgm.data)
+	}
+
+	// A zero radius leaves the image alone
+	src := randomPGM(5, 5, 256, 1)
+	got := copyPGM(src)
+	got.MedianFilter(0, WindowSquare)
+	if !samePGM(got, src) {
+		t.Error("radius 0 changed the image")
+	}
+}
+
+func TestPPMMedianFilter(t *testing.T) {
+	ppm := randomPPM(9, 6, 1)
+	r, g, b := ppm.Split()
+	ppm.MedianFilter(2, WindowCircle)
+	for _, plane := range []*PGM{r, g, b} {
+		plane.MedianFilter(2, WindowCircle)
+	}
+	for y := range ppm.data {
+		for x, px := range ppm.data[y] {
+			if want := (Pixel{r.data[y][x], g.data[y][x], b.data[y][x]}); px != want {
+				t.Fatalf("pixel (%d, %d) is %v, want %v", x, y, px, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package Netpbm
+
+import "math"
+
+// ResizeFilter selects how pixels are resampled when an image is resized,
+// rotated, sheared or warped. Rotate, Shear and Warp read single positions,
+// so they only accept NearestNeighbor, Bilinear and Bicubic.
+type ResizeFilter int
+
+const (
+	// NearestNeighbor copies the closest source pixel.
+	NearestNeighbor ResizeFilter = iota
+	// Bilinear interpolates linearly between neighbouring pixels.
+	Bilinear
+	// Bicubic uses the Catmull-Rom cubic kernel.
+	Bicubic
+	// Lanczos3 uses a three-lobed windowed sinc kernel.
+	Lanczos3
+	// AreaAverage averages every source pixel covered by the destination pixel,
+	// which gives the cleanest results when shrinking an image.
+	AreaAverage
+)
+
+// support returns the radius of the filter kernel in source pixels.
+func (f ResizeFilter) support() float64 {
+	switch f {
+	case Bilinear:
+		return 1
+	case Bicubic:
+		return 2
+	case Lanczos3:
+		return 3
+	}
+	return 0.5
+}
+
+// kernel evaluates the filter kernel at distance x.
+func (f ResizeFilter) kernel(x float64) float64 {
+	x = math.Abs(x)
+	switch f {
+	case Bilinear:
+		if x < 1 {
+			return 1 - x
+		}
+	case Bicubic:
+		return cubic(x)
+	case Lanczos3:
+		if x == 0 {
+			return 1
+		}
+		if x < 3 {
+			return 3 * math.Sin(math.Pi*x) * math.Sin(math.Pi*x/3) / (math.Pi * math.Pi * x * x)
+		}
+	}
+	return 0
+}
+
+// cubic is the Catmull-Rom kernel (a = -0.5).
+func cubic(x float64) float64 {
+	x = math.Abs(x)
+	switch {
+	case x < 1:
+		return 1.5*x*x*x - 2.5*x*x + 1
+	case x < 2:
+		return -0.5*x*x*x + 2.5*x*x - 4*x + 2
+	}
+	return 0
+}
+
+// contribution lists the source samples and weights that make up one destination sample.
+type contribution struct {
+	index  []int
+	weight []float64
+}
+
+// resampleWeights computes the contributions used to resample a line of
+// in samples into out samples.
+func resampleWeights(in, out int, filter ResizeFilter) []contribution {
+	scale := float64(in) / float64(out)
+	contributions := make([]contribution, out)
+
+	for i := 0; i < out; i++ {
+		c := &contributions[i]
+		center := (float64(i) + 0.5) * scale
+
+		switch filter {
+		case NearestNeighbor:
+			c.index = []int{clampIndex(int(center), in)}
+			c.weight = []float64{1}
+			continue
+		case AreaAverage:
+			// Weight every source pixel by how much of it the destination pixel covers
+			left, right := float64(i)*scale, float64(i+1)*scale
+			for j := int(left); float64(j) < right && j < in; j++ {
+				overlap := math.Min(right, float64(j+1)) - math.Max(left, float64(j))
+				if overlap > 0 {
+					c.index = append(c.index, j)
+					c.weight = append(c.weight, overlap)
+				}
+			}
+		default:
+			// Stretch the kernel when shrinking so that it acts as a low-pass filter
+			stretch := math.Max(scale, 1)
+			support := filter.support() * stretch
+			start := int(math.Floor(center - support))
+			end := int(math.Ceil(center + support))
+			for j := start; j <= end; j++ {
+				w := filter.kernel((float64(j) + 0.5 - center) / stretch)
+				if w != 0 {
+					c.index = append(c.index, clampIndex(j, in))
+					c.weight = append(c.weight, w)
+				}
+			}
+		}
+
+		// Normalize the weights so that flat areas keep their value
+		sum := 0.0
+		for _, w := range c.weight {
+			sum += w
+		}
+		if sum != 0 {
+			for k := range c.weight {
+				c.weight[k] /= sum
+			}
+		}
+	}
+	return contributions
+}
+
+// clampIndex keeps i inside [0, n).
+func clampIndex(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
+
+// resizeChannel resamples the channel to the given size, first horizontally and then vertically.
+func resizeChannel(src channel, width, height int, filter ResizeFilter) channel {
+	srcWidth, srcHeight := src.size()
+
+	// Horizontal pass
+	columns := resampleWeights(srcWidth, width, filter)
+	tmp := newChannel(width, srcHeight)
+	parallelRows(srcHeight, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x, c := range columns {
+				sum := 0.0
+				for k, i := range c.index {
+					sum += src[y][i] * c.weight[k]
+				}
+				tmp[y][x] = sum
+			}
+		}
+	})
+
+	// Vertical pass
+	rows := resampleWeights(srcHeight, height, filter)
+	dst := newChannel(width, height)
+	parallelRows(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			c := rows[y]
+			for k, i := range c.index {
+				w := c.weight[k]
+				for x := 0; x < width; x++ {
+					dst[y][x] += tmp[i][x] * w
+				}
+			}
+		}
+	})
+	return dst
+}
+
+// Resize scales the PGM image to the given size using the given filter.
+func (pgm *PGM) Resize(width, height int, filter ResizeFilter) {
+	if width <= 0 || height <= 0 || pgm.width == 0 || pgm.height == 0 {
+		return
+	}
+	pgm.data = resizeChannel(pgm.channel(), width, height, filter).toUint8(pgm.max)
+	pgm.width, pgm.height = width, height
+}
+
+// Resize scales the PPM image to the given size using the given filter.
+func (ppm *PPM) Resize(width, height int, filter ResizeFilter) {
+	if width <= 0 || height <= 0 || ppm.width == 0 || ppm.height == 0 {
+		return
+	}
+	ch := ppm.channels()
+	for i := range ch {
+		ch[i] = resizeChannel(ch[i], width, height, filter)
+	}
+	ppm.data = mergeChannels(ch, ppm.max)
+	ppm.width, ppm.height = width, height
+}
+
+// Resize scales the PBM image to the given size. The image is resampled as
+// gray levels and thresholded back to black and white.
+func (pbm *PBM) Resize(width, height int, filter ResizeFilter) {
+	if width <= 0 || height <= 0 || pbm.width == 0 || pbm.height == 0 {
+		return
+	}
+	gray := pbm.toPGM()
+	gray.Resize(width, height, filter)
+	pbm.fromPGM(gray)
+}
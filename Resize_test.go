@@ -0,0 +1,58 @@
+package Netpbm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResizeIdentity(t *testing.T) {
+	for _, filter := range []ResizeFilter{NearestNeighbor, Bilinear, Bicubic, Lanczos3, AreaAverage} {
+		want := randomPGM(13, 7, 256, 1)
+		got := copyPGM(want)
+		got.Resize(13, 7, filter)
+		if !samePGM(got, want) {
+			t.Errorf("filter %d: resizing to the same size changed the PGM image", filter)
+		}
+
+		ppm := randomPPM(5, 9, 2)
+		ppm.Resize(5, 9, filter)
+		if !reflect.DeepEqual(ppm.data, randomPPM(5, 9, 2).data) {
+			t.Errorf("filter %d: resizing to the same size changed the PPM image", filter)
+		}
+	}
+}
+
+func TestResizeNearestRoundTrip(t *testing.T) {
+	tests := []struct {
+		width, height int
+	}{
+		{1, 1},
+		{2, 3},
+		{13, 7},
+		{64, 5},
+	}
+	for _, tt := range tests {
+		want := randomPGM(tt.width, tt.height, 256, int64(tt.width))
+		got := copyPGM(want)
+		got.Resize(2*tt.width, 2*tt.height, NearestNeighbor)
+		for y := 0; y < got.height; y++ {
+			for x := 0; x < got.width; x++ {
+				if got.data[y][x] != want.data[y/2][x/2] {
+					t.Fatalf("%dx%d upscaled: pixel (%d, %d) is %d, want %d", tt.width, tt.height, x, y, got.data[y][x], want.data[y/2][x/2])
+				}
+			}
+		}
+		got.Resize(tt.width, tt.height, NearestNeighbor)
+		if !samePGM(got, want) {
+			t.Errorf("%dx%d: upscaling then downscaling by 2 changed the image", tt.width, tt.height)
+		}
+	}
+}
+
+func TestResizeAreaAverage(t *testing.T) {
+	pgm := &PGM{data: [][]uint8{{0, 10, 100, 100}, {20, 30, 100, 100}}, width: 4, height: 2, magicNumber: "P2", max: 255}
+	pgm.Resize(2, 1, AreaAverage)
+	if pgm.data[0][0] != 15 || pgm.data[0][1] != 100 {
+		t.Errorf("got %v, want [[15 100]]", pgm.data)
+	}
+}
@@ -0,0 +1,91 @@
+package Netpbm
+
+import "math"
+
+// rotatedSize returns the size of the canvas needed to hold a width x height
+// image rotated by angle radians.
+func rotatedSize(width, height int, angle float64) (int, int) {
+	cos, sin := math.Abs(math.Cos(angle)), math.Abs(math.Sin(angle))
+	w := float64(width)*cos + float64(height)*sin
+	h := float64(width)*sin + float64(height)*cos
+	// Ignore the rounding noise of the trigonometric functions
+	return int(math.Ceil(w - 1e-9)), int(math.Ceil(h - 1e-9))
+}
+
+// rotateChannel rotates the channel clockwise by angle degrees around its center.
+func rotateChannel(src channel, angle float64, filter ResizeFilter, expand bool, background float64) channel {
+	srcWidth, srcHeight := src.size()
+	rad := angle * math.Pi / 180
+	width, height := srcWidth, srcHeight
+	if expand {
+		width, height = rotatedSize(srcWidth, srcHeight, rad)
+	}
+
+	cos, sin := math.Cos(rad), math.Sin(rad)
+	srcCX, srcCY := float64(srcWidth-1)/2, float64(srcHeight-1)/2
+	dstCX, dstCY := float64(width-1)/2, float64(height-1)/2
+
+	dst := newChannel(width, height)
+	parallelRows(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			dy := float64(y) - dstCY
+			for x := 0; x < width; x++ {
+				dx := float64(x) - dstCX
+				// Apply the inverse rotation to find where the pixel comes from
+				sx := dx*cos + dy*sin + srcCX
+				sy := -dx*sin + dy*cos + srcCY
+				dst[y][x] = src.sample(sx, sy, filter, EdgeConstant, background)
+			}
+		}
+	})
+	return dst
+}
+
+// Rotate rotates the PGM image clockwise by angle degrees. When expand is
+// true the canvas grows to hold the whole rotated image, otherwise the result
+// is cropped to the original size. Uncovered areas are filled with background.
+// The filter must be NearestNeighbor, Bilinear or Bicubic.
+func (pgm *PGM) Rotate(angle float64, filter ResizeFilter, expand bool, background uint8) error {
+	if err := checkSampling(filter); err != nil {
+		return err
+	}
+	rotated := rotateChannel(pgm.channel(), angle, filter, expand, float64(background))
+	pgm.width, pgm.height = rotated.size()
+	pgm.data = rotated.toUint8(pgm.max)
+	return nil
+}
+
+// Rotate rotates the PPM image clockwise by angle degrees. When expand is
+// true the canvas grows to hold the whole rotated image, otherwise the result
+// is cropped to the original size. Uncovered areas are filled with background.
+// The filter must be NearestNeighbor, Bilinear or Bicubic.
+func (ppm *PPM) Rotate(angle float64, filter ResizeFilter, expand bool, background Pixel) error {
+	if err := checkSampling(filter); err != nil {
+		return err
+	}
+	ch := ppm.channels()
+	fill := pixelSamples(background)
+	for i := range ch {
+		ch[i] = rotateChannel(ch[i], angle, filter, expand, fill[i])
+	}
+	ppm.width, ppm.height = ch[0].size()
+	ppm.data = mergeChannels(ch, ppm.max)
+	return nil
+}
+
+// Rotate rotates the PBM image clockwise by angle degrees. The image is
+// sampled as gray levels and thresholded back to black and white. Uncovered
+// areas are black when background is true and white otherwise. The filter
+// must be NearestNeighbor, Bilinear or Bicubic.
+func (pbm *PBM) Rotate(angle float64, filter ResizeFilter, expand bool, background bool) error {
+	gray := pbm.toPGM()
+	fill := uint8(255)
+	if background {
+		fill = 0
+	}
+	if err := gray.Rotate(angle, filter, expand, fill); err != nil {
+		return err
+	}
+	pbm.fromPGM(gray)
+	return nil
+}
@@ -0,0 +1,74 @@
+package Netpbm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRotateRightAngles(t *testing.T) {
+	tests := []struct {
+		angle float64
+		turn  func(pgm *PGM)
+	}{
+		{0, func(pgm *PGM) {}},
+		{90, func(pgm *PGM) { pgm.Rotate90CW() }},
+		{180, func(pgm *PGM) { pgm.Rotate90CW(); pgm.Rotate90CW() }},
+		{-90, func(pgm *PGM) { pgm.Rotate90CW(); pgm.Rotate90CW(); pgm.Rotate90CW() }},
+		{450, func(pgm *PGM) { pgm.Rotate90CW() }},
+	}
+	for _, filter := range []ResizeFilter{NearestNeighbor, Bilinear, Bicubic} {
+		for _, size := range [][2]int{{1, 1}, {4, 4}, {7, 3}, {6, 9}} {
+			for _, tt := range tests {
+				want := randomPGM(size[0], size[1], 256, int64(size[0]*size[1]))
+				got := copyPGM(want)
+				tt.turn(want)
+				if err := got.Rotate(tt.angle, filter, true, 0); err != nil {
+					t.Fatal(err)
+				}
+				if !samePGM(got, want) {
+					t.Errorf("filter %d, %dx%d: Rotate(%g) does not match Rotate90CW", filter, size[0], size[1], tt.angle)
+				}
+			}
+		}
+	}
+}
+
+func TestRotatePPM(t *testing.T) {
+	want := randomPPM(5, 3, 1)
+	got := randomPPM(5, 3, 1)
+	want.Rotate90CW()
+	if err := got.Rotate(90, Bilinear, true, Pixel{}); err != nil {
+		t.Fatal(err)
+	}
+	if got.width != want.width || got.height != want.height || !reflect.DeepEqual(got.data, want.data) {
+		t.Error("PPM Rotate(90) does not match Rotate90CW")
+	}
+}
+
+func TestRotateCrop(t *testing.T) {
+	// Rotating a wide image by 90 degrees without expanding keeps its size and
+	// fills the corners with the background
+	pgm := randomPGM(5, 1, 1, 0)
+	for x := range pgm.data[0] {
+		pgm.data[0][x] = 100
+	}
+	if err := pgm.Rotate(90, NearestNeighbor, false, 7); err != nil {
+		t.Fatal(err)
+	}
+	want := []uint8{7, 7, 100, 7, 7}
+	if pgm.width != 5 || pgm.height != 1 || !reflect.DeepEqual(pgm.data[0], want) {
+		t.Errorf("got %dx%d %v, want 5x1 %v", pgm.width, pgm.height, pgm.data, want)
+	}
+}
+
+func TestRotateFilters(t *testing.T) {
+	for _, filter := range []ResizeFilter{Lanczos3, AreaAverage} {
+		pgm := randomPGM(3, 3, 256, 0)
+		if err := pgm.Rotate(30, filter, true, 0); err == nil {
+			t.Errorf("filter %d: got no error", filter)
+		}
+		if pgm.width != 3 || pgm.height != 3 {
+			t.Errorf("filter %d: image resized on error", filter)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package Netpbm
+
+import (
+	"errors"
+	"math"
+)
+
+// EdgeMode selects which value is read for pixels that lie outside of the image.
+type EdgeMode int
+
+const (
+	// EdgeConstant reads a fixed fill value.
+	EdgeConstant EdgeMode = iota
+	// EdgeClamp repeats the nearest edge pixel.
+	EdgeClamp
+	// EdgeWrap tiles the image, reading from the opposite edge.
+	EdgeWrap
+	// EdgeReflect mirrors the image around its edge pixels.
+	EdgeReflect
+)
+
+// EdgeReplicate is another name for EdgeClamp.
+const EdgeReplicate = EdgeClamp
+
+// edgeIndex maps the coordinate i onto [0, n) according to the edge mode.
+// It returns false when the pixel should read the constant fill value.
+func edgeIndex(i, n int, edge EdgeMode) (int, bool) {
+	if i >= 0 && i < n {
+		return i, true
+	}
+	if n == 0 {
+		return 0, false
+	}
+	switch edge {
+	case EdgeClamp:
+		return clampIndex(i, n), true
+	case EdgeWrap:
+		i %= n
+		if i < 0 {
+			i += n
+		}
+		return i, true
+	case EdgeReflect:
+		if n == 1 {
+			return 0, true
+		}
+		// The mirrored image repeats every 2(n-1) pixels: abcd -> abcdcb
+		period := 2 * (n - 1)
+		i %= period
+		if i < 0 {
+			i += period
+		}
+		if i >= n {
+			i = period - i
+		}
+		return i, true
+	}
+	return 0, false
+}
+
+// at returns the sample at (x, y), handling positions outside of the channel
+// according to the edge mode.
+func (c channel) at(x, y int, edge EdgeMode, outside float64) float64 {
+	width, height := c.size()
+	x, okX := edgeIndex(x, width, edge)
+	y, okY := edgeIndex(y, height, edge)
+	if !okX || !okY {
+		return outside
+	}
+	return c[y][x]
+}
+
+// checkSampling returns an error unless the filter can read a single pixel
+// at a position between pixel centers, as Rotate, Warp and Shear do. Only
+// NearestNeighbor, Bilinear and Bicubic can; Lanczos3 and AreaAverage are
+// meant for resizing.
+func checkSampling(filter ResizeFilter) error {
+	switch filter {
+	case NearestNeighbor, Bilinear, Bicubic:
+		return nil
+	}
+	return errors.New("only NearestNeighbor, Bilinear and Bicubic can be used for sampling")
+}
+
+// sample reads the channel at the real position (x, y), where pixel centers
+// lie on integer coordinates. Bilinear blends the four surrounding pixels,
+// Bicubic the sixteen surrounding pixels with the Catmull-Rom kernel, and any
+// other filter reads the closest pixel.
+func (c channel) sample(x, y float64, filter ResizeFilter, edge EdgeMode, outside float64) float64 {
+	switch filter {
+	case Bilinear:
+		x0, y0 := math.Floor(x), math.Floor(y)
+		fx, fy := x-x0, y-y0
+		ix, iy := int(x0), int(y0)
+		top := c.at(ix, iy, edge, outside)*(1-fx) + c.at(ix+1, iy, edge, outside)*fx
+		bottom := c.at(ix, iy+1, edge, outside)*(1-fx) + c.at(ix+1, iy+1, edge, outside)*fx
+		return top*(1-fy) + bottom*fy
+	case Bicubic:
+		x0, y0 := math.Floor(x), math.Floor(y)
+		fx, fy := x-x0, y-y0
+		ix, iy := int(x0), int(y0)
+		sum := 0.0
+		for j := -1; j <= 2; j++ {
+			wy := cubic(float64(j) - fy)
+			row := 0.0
+			for i := -1; i <= 2; i++ {
+				row += c.at(ix+i, iy+j, edge, outside) * cubic(float64(i)-fx)
+			}
+			sum += row * wy
+		}
+		return sum
+	}
+	return c.at(int(math.Round(x)), int(math.Round(y)), edge, outside)
+}
@@ -0,0 +1,76 @@
+package Netpbm
+
+import "math"
+
+// sharpenKernel boosts each pixel against its four direct neighbours.
+var sharpenKernel = [][]float64{
+	{0, -1, 0},
+	{-1, 5, -1},
+	{0, -1, 0},
+}
+
+// unsharpChannel adds amount times the difference between the channel and its
+// Gaussian blur of standard deviation radius, wherever that difference
+// reaches threshold.
+func unsharpChannel(c channel, radius, amount float64, threshold uint8) channel {
+	blurred := gaussianBlurChannel(c, radius)
+	width, height := c.size()
+	out := newChannel(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			diff := c[y][x] - blurred[y][x]
+			out[y][x] = c[y][x]
+			if math.Abs(diff) >= float64(threshold) {
+				out[y][x] += amount * diff
+			}
+		}
+	}
+	return out
+}
+
+// Sharpen sharpens the PGM image with a small fixed kernel.
+func (pgm *PGM) Sharpen() {
+	pgm.applyFilter(func(c channel) channel { return convolveChannel(c, sharpenKernel, EdgeClamp) })
+}
+
+// Sharpen sharpens each channel of the PPM image with a small fixed kernel.
+func (ppm *PPM) Sharpen() {
+	ppm.applyFilter(func(c channel) channel { return convolveChannel(c, sharpenKernel, EdgeClamp) })
+}
+
+// UnsharpMask sharpens the PGM image by adding back amount times the details
+// removed by a Gaussian blur of standard deviation radius. Details smaller
+// than threshold are left alone so that noise is not amplified.
+func (pgm *PGM) UnsharpMask(radius, amount float64, threshold uint8) {
+	pgm.applyFilter(func(c channel) channel { return unsharpChannel(c, radius, amount, threshold) })
+}
+
+// UnsharpMask sharpens each channel of the PPM image like PGM.UnsharpMask.
+func (ppm *PPM) UnsharpMask(radius, amount float64, threshold uint8) {
+	ppm.applyFilter(func(c channel) channel { return unsharpChannel(c, radius, amount, threshold) })
+}
+
+// UnsharpMaskLuminance sharpens the PPM image like UnsharpMask, but works on
+// the luminance only and adds the same correction to the three channels, so
+// that edges do not get colored fringes.
+func (ppm *PPM) UnsharpMaskLuminance(radius, amount float64, threshold uint8) {
+	lum := newChannel(ppm.width, ppm.height)
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			p := ppm.data[y][x]
+			lum[y][x] = 0.299*float64(p.R) + 0.587*float64(p.G) + 0.114*float64(p.B)
+		}
+	}
+	sharpened := unsharpChannel(lum, radius, amount, threshold)
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			delta := sharpened[y][x] - lum[y][x]
+			c := pixelSamples(ppm.data[y][x])
+			ppm.data[y][x] = Pixel{
+				R: clampSample(c[0]+delta, ppm.max),
+				G: clampSample(c[1]+delta, ppm.max),
+				B: clampSample(c[2]+delta, ppm.max),
+			}
+		}
+	}
+}
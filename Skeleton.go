@@ -0,0 +1,257 @@
+package Netpbm
+
+import "math"
+
+// ring lists the eight neighbours of a pixel clockwise from the one above,
+// named P2 to P9 in the thinning papers.
+var ring = [8]Point{{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}}
+
+// neighbours returns whether the neighbours of (x, y) are black, in ring
+// order. Pixels outside of the image are white.
+func neighbours(data [][]bool, x, y int) [8]bool {
+	var n [8]bool
+	for i, d := range ring {
+		nx, ny := x+d.X, y+d.Y
+		n[i] = ny >= 0 && ny < len(data) && nx >= 0 && nx < len(data[ny]) && data[ny][nx]
+	}
+	return n
+}
+
+// blackCount returns the number of black neighbours.
+func blackCount(n [8]bool) int {
+	count := 0
+	for _, b := range n {
+		if b {
+			count++
+		}
+	}
+	return count
+}
+
+// transitions returns the number of white to black changes going once around
+// the neighbours, which is the number of separate black arcs around the pixel.
+func transitions(n [8]bool) int {
+	count := 0
+	for i := range n {
+		if !n[i] && n[(i+1)%8] {
+			count++
+		}
+	}
+	return count
+}
+
+// thinCopy returns a copy of the PBM image to be thinned.
+func (pbm *PBM) thinCopy() *PBM {
+	thin := &PBM{data: make([][]bool, pbm.height), width: pbm.width, height: pbm.height, magicNumber: pbm.magicNumber}
+	for y := range thin.data {
+		thin.data[y] = append([]bool(nil), pbm.data[y]...)
+	}
+	return thin
+}
+
+// thin removes, two sub-iterations at a time, the black pixels at p for which
+// deletable returns true, until none is left. Every sub-iteration decides on
+// all the pixels first and removes them afterwards.
+func (pbm *PBM) thin(deletable func(p Point, n [8]bool, pass int) bool) *PBM {
+	thin := pbm.thinCopy()
+	var marked []Point
+	for changed := true; changed; {
+		changed = false
+		for pass := 0; pass < 2; pass++ {
+			marked = marked[:0]
+			for y := 0; y < thin.height; y++ {
+				for x := 0; x < thin.width; x++ {
+					p := Point{x, y}
+					if thin.data[y][x] && deletable(p, neighbours(thin.data, x, y), pass) {
+						marked = append(marked, p)
+					}
+				}
+			}
+			for _, p := range marked {
+				thin.data[p.Y][p.X] = false
+			}
+			changed = changed || len(marked) > 0
+		}
+	}
+	return thin
+}
+
+// zhangSuen reports whether a pixel with the given neighbours is removed by
+// the given sub-iteration of the Zhang-Suen algorithm.
+func zhangSuen(n [8]bool, pass int) bool {
+	b := blackCount(n)
+	if b < 2 || b > 6 || transitions(n) != 1 {
+		return false
+	}
+	p2, p4, p6, p8 := n[0], n[2], n[4], n[6]
+	if pass == 0 {
+		return !(p2 && p4 && p6) && !(p4 && p6 && p8)
+	}
+	return !(p2 && p4 && p8) && !(p2 && p6 && p8)
+}
+
+// ThinZhangSuen returns a new PBM image holding the one pixel wide skeleton
+// of the black shapes of the PBM image, computed with the Zhang-Suen
+// thinning algorithm.
+func (pbm *PBM) ThinZhangSuen() *PBM {
+	return pbm.thin(func(_ Point, n [8]bool, pass int) bool { return zhangSuen(n, pass) })
+}
+
+// ThinGuoHall returns a new PBM image holding the one pixel wide skeleton of
+// the black shapes of the PBM image, computed with the Guo-Hall thinning
+// algorithm, which keeps diagonal lines thinner than Zhang-Suen.
+func (pbm *PBM) ThinGuoHall() *PBM {
+	bit := func(b bool) int {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	return pbm.thin(func(_ Point, n [8]bool, pass int) bool {
+		p2, p3, p4, p5, p6, p7, p8, p9 := n[0], n[1], n[2], n[3], n[4], n[5], n[6], n[7]
+		c := bit(!p2 && (p3 || p4)) + bit(!p4 && (p5 || p6)) + bit(!p6 && (p7 || p8)) + bit(!p8 && (p9 || p2))
+		n1 := bit(p9 || p2) + bit(p3 || p4) + bit(p5 || p6) + bit(p7 || p8)
+		n2 := bit(p2 || p3) + bit(p4 || p5) + bit(p6 || p7) + bit(p8 || p9)
+		count := min(n1, n2)
+		m := (p6 || p7 || !p9) && p8
+		if pass == 1 {
+			m = (p2 || p3 || !p5) && p4
+		}
+		return c == 1 && count >= 2 && count <= 3 && !m
+	})
+}
+
+// distance1D computes in place the squared distance transform of a line of
+// squared distances, with the lower envelope of parabolas of Felzenszwalb and
+// Huttenlocher.
+func distance1D(f []float64) {
+	n := len(f)
+	if n == 0 {
+		return
+	}
+	d := make([]float64, n)
+	v := make([]int, n)
+	z := make([]float64, n+1)
+	k := 0
+	z[0], z[1] = math.Inf(-1), math.Inf(1)
+	for q := 1; q < n; q++ {
+		s := ((f[q] + float64(q*q)) - (f[v[k]] + float64(v[k]*v[k]))) / float64(2*q-2*v[k])
+		for k > 0 && s <= z[k] {
+			k--
+			s = ((f[q] + float64(q*q)) - (f[v[k]] + float64(v[k]*v[k]))) / float64(2*q-2*v[k])
+		}
+		k++
+		v[k], z[k], z[k+1] = q, s, math.Inf(1)
+	}
+	k = 0
+	for q := 0; q < n; q++ {
+		for z[k+1] < float64(q) {
+			k++
+		}
+		d[q] = float64((q-v[k])*(q-v[k])) + f[v[k]]
+	}
+	copy(f, d)
+}
+
+// distanceTransform returns, for every black pixel of the PBM image, the
+// squared Euclidean distance to the nearest white pixel, pixels outside of
+// the image being white.
+func (pbm *PBM) distanceTransform() channel {
+	// Pad by one white pixel on every side so that the border counts as white,
+	// which also leaves a white pixel on every line of the first pass
+	width, height := pbm.width+2, pbm.height+2
+	far := float64(width*width + height*height)
+	dist := newChannel(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x > 0 && y > 0 && x <= pbm.width && y <= pbm.height && pbm.data[y-1][x-1] {
+				dist[y][x] = far
+			}
+		}
+	}
+	column := make([]float64, height)
+	for x := 0; x < width; x++ {
+		for y := range column {
+			column[y] = dist[y][x]
+		}
+		distance1D(column)
+		for y := range column {
+			dist[y][x] = column[y]
+		}
+	}
+	for y := range dist {
+		distance1D(dist[y])
+	}
+
+	inner := newChannel(pbm.width, pbm.height)
+	for y := range inner {
+		copy(inner[y], dist[y+1][1:pbm.width+1])
+	}
+	return inner
+}
+
+// MedialAxis returns a new PBM image holding the medial axis skeleton of the
+// black shapes of the PBM image. The centers of the largest disks that fit
+// in the shapes, found on their Euclidean distance to the background with
+// half a pixel of tolerance for the steps of the outline, are kept while the
+// rest is thinned like ThinZhangSuen, then the result is thinned with
+// ThinGuoHall to be one pixel wide. Unlike plain thinning, the axis runs to
+// the far ends of elongated shapes.
+func (pbm *PBM) MedialAxis() *PBM {
+	dist := pbm.distanceTransform()
+	centers := make([][]bool, pbm.height)
+	for y := range centers {
+		centers[y] = make([]bool, pbm.width)
+		for x := range centers[y] {
+			if !pbm.data[y][x] {
+				continue
+			}
+			// A disk is not the largest if a neighbour's disk contains it
+			r := math.Sqrt(dist[y][x])
+			centers[y][x] = true
+			for _, d := range ring {
+				nx, ny := x+d.X, y+d.Y
+				if nx < 0 || ny < 0 || nx >= pbm.width || ny >= pbm.height {
+					continue
+				}
+				if math.Sqrt(dist[ny][nx]) >= r+math.Hypot(float64(d.X), float64(d.Y))-0.5 {
+					centers[y][x] = false
+					break
+				}
+			}
+		}
+	}
+
+	anchored := pbm.thin(func(p Point, n [8]bool, pass int) bool {
+		return !centers[p.Y][p.X] && zhangSuen(n, pass)
+	})
+	return anchored.ThinGuoHall()
+}
+
+// EndPoints returns the black pixels of the PBM image that have exactly one
+// black neighbour, the ends of the lines of a skeleton.
+func (pbm *PBM) EndPoints() []Point {
+	var points []Point
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if pbm.data[y][x] && blackCount(neighbours(pbm.data, x, y)) == 1 {
+				points = append(points, Point{x, y})
+			}
+		}
+	}
+	return points
+}
+
+// BranchPoints returns the black pixels of the PBM image from which three or
+// more separate lines leave, the junctions of a skeleton.
+func (pbm *PBM) BranchPoints() []Point {
+	var points []Point
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if pbm.data[y][x] && transitions(neighbours(pbm.data, x, y)) >= 3 {
+				points = append(points, Point{x, y})
+			}
+		}
+	}
+	return points
+}
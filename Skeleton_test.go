@@ -0,0 +1,153 @@
+package Netpbm
+
+import (
+	"reflect"
+	"testing"
+)
+
+// bitmapPBM returns a PBM image of the given size with the rectangles
+// {x0, y0, x1, y1}, bounds included, painted black.
+func bitmapPBM(width, height int, rects ...[4]int) *PBM {
+	pbm := &PBM{data: make([][]bool, height), width: width, height: height, magicNumber: "P1"}
+	for y := range pbm.data {
+		pbm.data[y] = make([]bool, width)
+	}
+	for _, r := range rects {
+		for y := r[1]; y <= r[3]; y++ {
+			for x := r[0]; x <= r[2]; x++ {
+				pbm.data[y][x] = true
+			}
+		}
+	}
+	return pbm
+}
+
+// components returns the number of 8-connected black shapes of the PBM image.
+func components(pbm *PBM) int {
+	seen := make(map[Point]bool)
+	count := 0
+	for y := range pbm.data {
+		for x, black := range pbm.data[y] {
+			if !black || seen[Point{x, y}] {
+				continue
+			}
+			count++
+			stack := []Point{{x, y}}
+			seen[Point{x, y}] = true
+			for len(stack) > 0 {
+				p := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				for _, d := range ring {
+					q := Point{p.X + d.X, p.Y + d.Y}
+					if q.Y >= 0 && q.Y < pbm.height && q.X >= 0 && q.X < pbm.width && pbm.data[q.Y][q.X] && !seen[q] {
+						seen[q] = true
+						stack = append(stack, q)
+					}
+				}
+			}
+		}
+	}
+	return count
+}
+
+// checkSkeleton reports the ways the skeleton fails to be a one pixel wide,
+// connected subset of src.
+func checkSkeleton(t *testing.T, name string, src, skel *PBM) {
+	t.Helper()
+	for y := range skel.data {
+		for x, black := range skel.data[y] {
+			if black && !src.data[y][x] {
+				t.Errorf("%s: pixel (%d, %d) is outside of the shape", name, x, y)
+			}
+			if y > 0 && x > 0 && black && skel.data[y-1][x] && skel.data[y][x-1] && skel.data[y-1][x-1] {
+				t.Errorf("%s: 2x2 block at (%d, %d)", name, x-1, y-1)
+			}
+		}
+	}
+	if got := components(skel); got != 1 {
+		t.Errorf("%s: skeleton has %d parts, want 1", name, got)
+	}
+}
+
+func TestThinBar(t *testing.T) {
+	bar := bitmapPBM(30, 11, [4]int{4, 3, 25, 7})
+	want := bitmapPBM(30, 11, [4]int{4, 3, 25, 7})
+	thinnings := []struct {
+		name string
+		thin func(pbm *PBM) *PBM
+	}{
+		{"Zhang-Suen", (*PBM).ThinZhangSuen},
+		{"Guo-Hall", (*PBM).ThinGuoHall},
+	}
+	for _, th := range thinnings {
+		skel := th.thin(bar)
+		if !reflect.DeepEqual(bar.data, want.data) {
+			t.Fatalf("%s: source image changed", th.name)
+		}
+		checkSkeleton(t, th.name, bar, skel)
+		// A horizontal line: at most one pixel per column
+		for x := 0; x < skel.width; x++ {
+			n := 0
+			for y := 0; y < skel.height; y++ {
+				if skel.data[y][x] {
+					n++
+				}
+			}
+			if n > 1 {
+				t.Errorf("%s: column %d has %d black pixels", th.name, x, n)
+			}
+		}
+		if ends := skel.EndPoints(); len(ends) != 2 {
+			t.Errorf("%s: got end points %v, want 2", th.name, ends)
+		}
+		if branches := skel.BranchPoints(); len(branches) != 0 {
+			t.Errorf("%s: got branch points %v, want none", th.name, branches)
+		}
+	}
+}
+
+func TestThinRing(t *testing.T) {
+	// A thick square frame thins to a closed loop without ends
+	frame := bitmapPBM(20, 20, [4]int{2, 2, 17, 5}, [4]int{2, 14, 17, 17}, [4]int{2, 2, 5, 17}, [4]int{14, 2, 17, 17})
+	skeletons := []struct {
+		name string
+		skel *PBM
+	}{
+		{"Zhang-Suen", frame.ThinZhangSuen()},
+		{"Guo-Hall", frame.ThinGuoHall()},
+	}
+	for _, s := range skeletons {
+		name, skel := s.name, s.skel
+		checkSkeleton(t, name, frame, skel)
+		if ends := skel.EndPoints(); len(ends) != 0 {
+			t.Errorf("%s: got end points %v, want none", name, ends)
+		}
+		if skel.data[10][10] {
+			t.Errorf("%s: the hole was filled", name)
+		}
+	}
+}
+
+func TestMedialAxis(t *testing.T) {
+	bar := bitmapPBM(30, 11, [4]int{4, 3, 25, 7})
+	skel := bar.MedialAxis()
+	checkSkeleton(t, "medial axis", bar, skel)
+	// The middle row of the bar is on the axis
+	for x := 8; x <= 21; x++ {
+		if !skel.data[5][x] {
+			t.Errorf("pixel (%d, 5) is not on the axis", x)
+		}
+	}
+}
+
+func TestEndAndBranchPoints(t *testing.T) {
+	plus := bitmapPBM(7, 7, [4]int{3, 0, 3, 6}, [4]int{0, 3, 6, 3})
+	ends := plus.EndPoints()
+	want := []Point{{3, 0}, {0, 3}, {6, 3}, {3, 6}}
+	if !reflect.DeepEqual(ends, want) {
+		t.Errorf("got end points %v, want %v", ends, want)
+	}
+	if branches := plus.BranchPoints(); !reflect.DeepEqual(branches, []Point{{3, 3}}) {
+		t.Errorf("got branch points %v, want [{3 3}]", branches)
+	}
+}
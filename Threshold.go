@@ -0,0 +1,218 @@
+package Netpbm
+
+import "math"
+
+// ThresholdMethod selects how Threshold decides which pixels become black.
+type ThresholdMethod int
+
+const (
+	// ThresholdOtsu picks the global level that best separates the histogram
+	// into two classes.
+	ThresholdOtsu ThresholdMethod = iota
+	// ThresholdTriangle picks the global level farthest from the line joining
+	// the histogram peak to the end of its longest tail. It suits images with
+	// a large background and little foreground.
+	ThresholdTriangle
+	// ThresholdSauvola compares each pixel with mean * (1 + K * (stddev/R - 1))
+	// over its window. It copes well with uneven lighting on text.
+	ThresholdSauvola
+	// ThresholdNiblack compares each pixel with mean + K * stddev over its window.
+	ThresholdNiblack
+	// ThresholdMeanC compares each pixel with the mean of its window minus C.
+	ThresholdMeanC
+)
+
+// ThresholdOptions configures Threshold. Zero values, and a nil K, select the
+// usual defaults.
+type ThresholdOptions struct {
+	// Method is the thresholding algorithm.
+	Method ThresholdMethod
+	// Window is the side of the square window of the local methods, 15 by default.
+	Window int
+	// K weights the standard deviation, 0.5 by default for Sauvola and -0.2 for
+	// Niblack. It is a pointer so that 0, which reduces both methods to a plain
+	// local mean threshold, can be asked for.
+	K *float64
+	// R is the dynamic range of the standard deviation for Sauvola, half of the max value by default.
+	R float64
+	// C is subtracted from the mean by ThresholdMeanC.
+	C float64
+}
+
+// OtsuLevel returns the level that maximizes the variance between the values
+// up to it and the values above it.
+func (h Histogram) OtsuLevel() int {
+	total := h.Total()
+	if total == 0 {
+		return 0
+	}
+	sumAll := 0.0
+	for v, n := range h {
+		sumAll += float64(v) * float64(n)
+	}
+
+	best, bestVariance := 0, -1.0
+	count, sum := 0, 0.0
+	for v, n := range h {
+		count += n
+		sum += float64(v) * float64(n)
+		if count == 0 || count == total {
+			continue
+		}
+		w0 := float64(count) / float64(total)
+		mean0 := sum / float64(count)
+		mean1 := (sumAll - sum) / float64(total-count)
+		variance := w0 * (1 - w0) * (mean0 - mean1) * (mean0 - mean1)
+		if variance > bestVariance {
+			best, bestVariance = v, variance
+		}
+	}
+	return best
+}
+
+// TriangleLevel returns the level found by the triangle method.
+func (h Histogram) TriangleLevel() int {
+	if h.Total() == 0 {
+		return 0
+	}
+	lo, hi := h.Min(), h.Max()
+	peak := lo
+	for v := lo; v <= hi; v++ {
+		if h[v] > h[peak] {
+			peak = v
+		}
+	}
+
+	// Walk along the longest tail, from the peak to the end of the histogram
+	end, step := hi, 1
+	if peak-lo > hi-peak {
+		end, step = lo, -1
+	}
+	if end == peak {
+		return peak
+	}
+
+	// Distance of each bin to the line from (peak, h[peak]) to (end, h[end])
+	dx, dy := float64(end-peak), float64(h[end]-h[peak])
+	best, bestDistance := peak, -1.0
+	for v := peak; v != end; v += step {
+		d := math.Abs(dy*float64(v-peak) - dx*float64(h[v]-h[peak]))
+		if d > bestDistance {
+			best, bestDistance = v, d
+		}
+	}
+	return best
+}
+
+// integralImage holds the running sums of the samples and of their squares,
+// so that the mean and variance of any rectangle take constant time.
+type integralImage struct {
+	sum, squares  [][]float64
+	width, height int
+}
+
+// newIntegralImage builds the integral image of the channel.
+func newIntegralImage(c channel) *integralImage {
+	width, height := c.size()
+	ii := &integralImage{
+		sum:     newChannel(width+1, height+1),
+		squares: newChannel(width+1, height+1),
+		width:   width,
+		height:  height,
+	}
+	for y := 0; y < height; y++ {
+		rowSum, rowSquares := 0.0, 0.0
+		for x := 0; x < width; x++ {
+			v := c[y][x]
+			rowSum += v
+			rowSquares += v * v
+			ii.sum[y+1][x+1] = ii.sum[y][x+1] + rowSum
+			ii.squares[y+1][x+1] = ii.squares[y][x+1] + rowSquares
+		}
+	}
+	return ii
+}
+
+// stats returns the mean and standard deviation of the window of the given
+// radius around (x, y), clipped to the image.
+func (ii *integralImage) stats(x, y, radius int) (float64, float64) {
+	x0, y0 := max(x-radius, 0), max(y-radius, 0)
+	x1, y1 := min(x+radius+1, ii.width), min(y+radius+1, ii.height)
+	n := float64((x1 - x0) * (y1 - y0))
+	sum := ii.sum[y1][x1] - ii.sum[y0][x1] - ii.sum[y1][x0] + ii.sum[y0][x0]
+	squares := ii.squares[y1][x1] - ii.squares[y0][x1] - ii.squares[y1][x0] + ii.squares[y0][x0]
+	mean := sum / n
+	variance := squares/n - mean*mean
+	return mean, math.Sqrt(math.Max(variance, 0))
+}
+
+// Threshold converts the PGM image to PBM with an automatic global level or
+// an adaptive local one, dark pixels becoming black.
+func (pgm *PGM) Threshold(opts ThresholdOptions) *PBM {
+	pbm := &PBM{
+		data:        make([][]bool, pgm.height),
+		width:       pgm.width,
+		height:      pgm.height,
+		magicNumber: "P1",
+	}
+	for y := range pbm.data {
+		pbm.data[y] = make([]bool, pgm.width)
+	}
+
+	switch opts.Method {
+	case ThresholdOtsu, ThresholdTriangle:
+		h := pgm.Histogram()
+		level := h.OtsuLevel()
+		if opts.Method == ThresholdTriangle {
+			level = h.TriangleLevel()
+		}
+		for y := 0; y < pgm.height; y++ {
+			for x := 0; x < pgm.width; x++ {
+				pbm.data[y][x] = int(pgm.data[y][x]) <= level
+			}
+		}
+		return pbm
+	}
+
+	window := opts.Window
+	if window <= 0 {
+		window = 15
+	}
+	radius := window / 2
+	k := 0.5
+	if opts.Method == ThresholdNiblack {
+		k = -0.2
+	}
+	if opts.K != nil {
+		k = *opts.K
+	}
+	r := opts.R
+	if r <= 0 {
+		r = math.Max(float64(pgm.max)/2, 1)
+	}
+
+	ii := newIntegralImage(pgm.channel())
+	parallelRows(pgm.height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < pgm.width; x++ {
+				mean, stddev := ii.stats(x, y, radius)
+				var t float64
+				switch opts.Method {
+				case ThresholdSauvola:
+					t = mean * (1 + k*(stddev/r-1))
+				case ThresholdNiblack:
+					t = mean + k*stddev
+				default:
+					t = mean - opts.C
+				}
+				pbm.data[y][x] = float64(pgm.data[y][x]) < t
+			}
+		}
+	})
+	return pbm
+}
+
+// Threshold converts the PPM image to PBM like PGM.Threshold, working on its luminance.
+func (ppm *PPM) Threshold(opts ThresholdOptions) *PBM {
+	return ppm.ToPGM().Threshold(opts)
+}
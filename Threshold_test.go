@@ -0,0 +1,131 @@
+package Netpbm
+
+import (
+	"math"
+	"testing"
+)
+
+// betweenVariance returns the variance between the values up to level and
+// the values above it, the quantity Otsu's method maximizes.
+func betweenVariance(h Histogram, level int) float64 {
+	var n0, n1, s0, s1 float64
+	for v, n := range h {
+		if v <= level {
+			n0 += float64(n)
+			s0 += float64(v * n)
+		} else {
+			n1 += float64(n)
+			s1 += float64(v * n)
+		}
+	}
+	if n0 == 0 || n1 == 0 {
+		return 0
+	}
+	total := n0 + n1
+	return n0 / total * n1 / total * (s0/n0 - s1/n1) * (s0/n0 - s1/n1)
+}
+
+func TestOtsuLevel(t *testing.T) {
+	bimodal := make(Histogram, 256)
+	for v := 45; v <= 55; v++ {
+		bimodal[v] = 10
+	}
+	for v := 195; v <= 205; v++ {
+		bimodal[v] = 10
+	}
+	if got := bimodal.OtsuLevel(); got != 55 {
+		t.Errorf("bimodal histogram: got level %d, want 55", got)
+	}
+
+	// Unbalanced classes still split between the modes
+	unbalanced := make(Histogram, 256)
+	unbalanced[30], unbalanced[31], unbalanced[220] = 100, 50, 5
+	if got := unbalanced.OtsuLevel(); got < 31 || got >= 220 {
+		t.Errorf("unbalanced histogram: got level %d, want between 31 and 219", got)
+	}
+
+	// The level reaches the best variance of a brute-force search
+	for seed := int64(1); seed <= 5; seed++ {
+		h := randomPGM(20, 20, 256, seed).Histogram()
+		best := 0.0
+		for level := range h {
+			best = math.Max(best, betweenVariance(h, level))
+		}
+		if got := betweenVariance(h, h.OtsuLevel()); got < best-1e-9 {
+			t.Errorf("seed %d: level %d has variance %g, best is %g", seed, h.OtsuLevel(), got, best)
+		}
+	}
+}
+
+func TestTriangleLevel(t *testing.T) {
+	// A parabolic tail below the peak: the farthest point from the line
+	// joining (0, 1) to the peak at (200, 40001) is halfway
+	h := make(Histogram, 256)
+	for v := 0; v <= 200; v++ {
+		h[v] = v*v + 1
+	}
+	if got := h.TriangleLevel(); got != 100 {
+		t.Errorf("got level %d, want 100", got)
+	}
+}
+
+func TestThresholdGlobal(t *testing.T) {
+	pgm := &PGM{data: [][]uint8{{45, 50, 55}, {195, 200, 205}}, width: 3, height: 2, magicNumber: "P2", max: 255}
+	pbm := pgm.Threshold(ThresholdOptions{Method: ThresholdOtsu})
+	for y, row := range pbm.data {
+		for x, black := range row {
+			if black != (y == 0) {
+				t.Errorf("pixel (%d, %d) is %v", x, y, black)
+			}
+		}
+	}
+}
+
+func TestThresholdLocal(t *testing.T) {
+	pgm := randomPGM(19, 13, 256, 1)
+	stats := func(x, y, radius int) (float64, float64) {
+		var sum, squares, n float64
+		for sy := max(y-radius, 0); sy <= min(y+radius, pgm.height-1); sy++ {
+			for sx := max(x-radius, 0); sx <= min(x+radius, pgm.width-1); sx++ {
+				v := float64(pgm.data[sy][sx])
+				sum += v
+				squares += v * v
+				n++
+			}
+		}
+		mean := sum / n
+		return mean, math.Sqrt(math.Max(squares/n-mean*mean, 0))
+	}
+	zero, k := 0.0, 0.3
+	tests := []struct {
+		name string
+		opts ThresholdOptions
+		t    func(mean, stddev float64) float64
+	}{
+		{"mean-C", ThresholdOptions{Method: ThresholdMeanC, Window: 5, C: 4}, func(m, _ float64) float64 { return m - 4 }},
+		{"Niblack default", ThresholdOptions{Method: ThresholdNiblack, Window: 7}, func(m, s float64) float64 { return m - 0.2*s }},
+		{"Niblack K", ThresholdOptions{Method: ThresholdNiblack, Window: 7, K: &k}, func(m, s float64) float64 { return m + 0.3*s }},
+		{"Niblack K = 0", ThresholdOptions{Method: ThresholdNiblack, Window: 7, K: &zero}, func(m, _ float64) float64 { return m }},
+		{"Sauvola default", ThresholdOptions{Method: ThresholdSauvola, Window: 9}, func(m, s float64) float64 { return m * (1 + 0.5*(s/127.5-1)) }},
+		{"Sauvola K = 0", ThresholdOptions{Method: ThresholdSauvola, Window: 9, K: &zero}, func(m, _ float64) float64 { return m }},
+		{"default window", ThresholdOptions{Method: ThresholdMeanC}, func(m, _ float64) float64 { return m }},
+	}
+	for _, tt := range tests {
+		window := tt.opts.Window
+		if window == 0 {
+			window = 15
+		}
+		pbm := pgm.Threshold(tt.opts)
+		for y := range pgm.data {
+			for x, v := range pgm.data[y] {
+				level := tt.t(stats(x, y, window/2))
+				if math.Abs(float64(v)-level) < 1e-6 {
+					continue
+				}
+				if want := float64(v) < level; pbm.data[y][x] != want {
+					t.Fatalf("%s: pixel (%d, %d) is %v, want %v", tt.name, x, y, pbm.data[y][x], want)
+				}
+			}
+		}
+	}
+}
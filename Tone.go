@@ -0,0 +1,225 @@
+package Netpbm
+
+import (
+	"math"
+	"sort"
+)
+
+// LUT is a lookup table mapping every sample value in [0, max] to a new value.
+type LUT []uint8
+
+// newLUT builds the table for max by evaluating f on every sample value.
+func newLUT(max uint8, f func(v float64) float64) LUT {
+	lut := make(LUT, int(max)+1)
+	for v := range lut {
+		lut[v] = clampSample(f(float64(v)), max)
+	}
+	return lut
+}
+
+// lookup returns the new value of v, values beyond the table using its last entry.
+func (lut LUT) lookup(v uint8) uint8 {
+	if int(v) >= len(lut) {
+		return lut[len(lut)-1]
+	}
+	return lut[v]
+}
+
+// GammaLUT returns the table applying gamma correction, like pnmgamma.
+// Values of gamma above 1 brighten the midtones and values below 1 darken them.
+func GammaLUT(max uint8, gamma float64) LUT {
+	return newLUT(max, func(v float64) float64 {
+		if max == 0 || gamma <= 0 {
+			return v
+		}
+		return float64(max) * math.Pow(v/float64(max), 1/gamma)
+	})
+}
+
+// LevelsLUT returns the table that stretches [black, white] to [0, max],
+// like pnmnorm, with gamma applied to the midtones.
+func LevelsLUT(max, black, white uint8, gamma float64) LUT {
+	return newLUT(max, func(v float64) float64 {
+		if white <= black {
+			if v < float64(black) {
+				return 0
+			}
+			return float64(max)
+		}
+		t := math.Min(math.Max((v-float64(black))/float64(white-black), 0), 1)
+		if gamma > 0 {
+			t = math.Pow(t, 1/gamma)
+		}
+		return t * float64(max)
+	})
+}
+
+// BrightnessContrastLUT returns the table that scales values around the
+// middle of the range by contrast (1 keeping them unchanged) and then shifts
+// them by brightness, given as a fraction of max in [-1, 1].
+func BrightnessContrastLUT(max uint8, brightness, contrast float64) LUT {
+	mid := float64(max) / 2
+	return newLUT(max, func(v float64) float64 {
+		return (v-mid)*contrast + mid + brightness*float64(max)
+	})
+}
+
+// CurveLUT returns the table following a smooth curve through the control
+// points, X being the input value and Y the output value. The curve is a
+// monotone cubic spline, so it never overshoots between points, and it is
+// flat before the first point and after the last one.
+func CurveLUT(max uint8, points []Point) LUT {
+	if len(points) == 0 {
+		return newLUT(max, func(v float64) float64 { return v })
+	}
+
+	// Sort the points and drop duplicated inputs
+	sorted := make([]Point, len(points))
+	copy(sorted, points)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].X < sorted[j].X })
+	pts := sorted[:1]
+	for _, p := range sorted[1:] {
+		if p.X != pts[len(pts)-1].X {
+			pts = append(pts, p)
+		}
+	}
+	n := len(pts)
+	if n == 1 {
+		return newLUT(max, func(v float64) float64 { return float64(pts[0].Y) })
+	}
+
+	// Fritsch-Carlson tangents
+	slopes := make([]float64, n-1)
+	for i := range slopes {
+		slopes[i] = float64(pts[i+1].Y-pts[i].Y) / float64(pts[i+1].X-pts[i].X)
+	}
+	tangents := make([]float64, n)
+	tangents[0], tangents[n-1] = slopes[0], slopes[n-2]
+	for i := 1; i < n-1; i++ {
+		if slopes[i-1]*slopes[i] <= 0 {
+			tangents[i] = 0
+		} else {
+			tangents[i] = (slopes[i-1] + slopes[i]) / 2
+		}
+	}
+	for i, s := range slopes {
+		if s == 0 {
+			tangents[i], tangents[i+1] = 0, 0
+			continue
+		}
+		a, b := tangents[i]/s, tangents[i+1]/s
+		if r := a*a + b*b; r > 9 {
+			t := 3 / math.Sqrt(r)
+			tangents[i], tangents[i+1] = t*a*s, t*b*s
+		}
+	}
+
+	return newLUT(max, func(v float64) float64 {
+		if v <= float64(pts[0].X) {
+			return float64(pts[0].Y)
+		}
+		if v >= float64(pts[n-1].X) {
+			return float64(pts[n-1].Y)
+		}
+		i := sort.Search(n, func(i int) bool { return float64(pts[i].X) > v }) - 1
+		h := float64(pts[i+1].X - pts[i].X)
+		t := (v - float64(pts[i].X)) / h
+		// Cubic Hermite basis
+		h00 := 2*t*t*t - 3*t*t + 1
+		h10 := t*t*t - 2*t*t + t
+		h01 := -2*t*t*t + 3*t*t
+		h11 := t*t*t - t*t
+		return h00*float64(pts[i].Y) + h10*h*tangents[i] + h01*float64(pts[i+1].Y) + h11*h*tangents[i+1]
+	})
+}
+
+// ApplyLUT replaces every pixel value of the PGM image through the table.
+func (pgm *PGM) ApplyLUT(lut LUT) {
+	if len(lut) == 0 {
+		return
+	}
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pgm.data[y][x] = lut.lookup(pgm.data[y][x])
+		}
+	}
+}
+
+// ApplyLUT replaces every channel of every pixel of the PPM image through the table.
+func (ppm *PPM) ApplyLUT(lut LUT) {
+	ppm.ApplyChannelLUTs(lut, lut, lut)
+}
+
+// ApplyChannelLUTs replaces the red, green and blue channels of the PPM image
+// through their own tables. A nil table leaves its channel unchanged.
+func (ppm *PPM) ApplyChannelLUTs(r, g, b LUT) {
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			pixel := &ppm.data[y][x]
+			if len(r) > 0 {
+				pixel.R = r.lookup(pixel.R)
+			}
+			if len(g) > 0 {
+				pixel.G = g.lookup(pixel.G)
+			}
+			if len(b) > 0 {
+				pixel.B = b.lookup(pixel.B)
+			}
+		}
+	}
+}
+
+// Gamma applies gamma correction to the PGM image, see GammaLUT.
+func (pgm *PGM) Gamma(gamma float64) {
+	pgm.ApplyLUT(GammaLUT(pgm.max, gamma))
+}
+
+// Gamma applies gamma correction to the PPM image, see GammaLUT.
+func (ppm *PPM) Gamma(gamma float64) {
+	ppm.ApplyLUT(GammaLUT(ppm.max, gamma))
+}
+
+// Levels stretches the values of the PGM image between black and white to the full range.
+func (pgm *PGM) Levels(black, white uint8) {
+	pgm.ApplyLUT(LevelsLUT(pgm.max, black, white, 1))
+}
+
+// Levels stretches the values of the PPM image between black and white to the full range.
+func (ppm *PPM) Levels(black, white uint8) {
+	ppm.ApplyLUT(LevelsLUT(ppm.max, black, white, 1))
+}
+
+// Normalize stretches the PGM image so that the darkest low percent of the
+// pixels become black and the brightest high percent become white, like pnmnorm.
+func (pgm *PGM) Normalize(low, high float64) {
+	h := pgm.Histogram()
+	pgm.Levels(uint8(h.Percentile(low)), uint8(h.Percentile(100-high)))
+}
+
+// Normalize stretches the PPM image so that the darkest low percent of the
+// pixels become black and the brightest high percent become white, like
+// pnmnorm. The same levels are applied to every channel.
+func (ppm *PPM) Normalize(low, high float64) {
+	h := ppm.Histogram().Luminance
+	ppm.Levels(uint8(h.Percentile(low)), uint8(h.Percentile(100-high)))
+}
+
+// BrightnessContrast adjusts the brightness and contrast of the PGM image, see BrightnessContrastLUT.
+func (pgm *PGM) BrightnessContrast(brightness, contrast float64) {
+	pgm.ApplyLUT(BrightnessContrastLUT(pgm.max, brightness, contrast))
+}
+
+// BrightnessContrast adjusts the brightness and contrast of the PPM image, see BrightnessContrastLUT.
+func (ppm *PPM) BrightnessContrast(brightness, contrast float64) {
+	ppm.ApplyLUT(BrightnessContrastLUT(ppm.max, brightness, contrast))
+}
+
+// Curves maps the values of the PGM image through a curve, see CurveLUT.
+func (pgm *PGM) Curves(points []Point) {
+	pgm.ApplyLUT(CurveLUT(pgm.max, points))
+}
+
+// Curves maps the values of the PPM image through a curve, see CurveLUT.
+func (ppm *PPM) Curves(points []Point) {
+	ppm.ApplyLUT(CurveLUT(ppm.max, points))
+}
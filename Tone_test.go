@@ -0,0 +1,68 @@
+package Netpbm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToneLUTs(t *testing.T) {
+	tests := []struct {
+		name string
+		lut  LUT
+		want map[int]uint8
+	}{
+		{"gamma 1", GammaLUT(255, 1), map[int]uint8{0: 0, 64: 64, 255: 255}},
+		{"gamma 2", GammaLUT(255, 2), map[int]uint8{0: 0, 64: 128, 255: 255}},
+		{"gamma 0.5", GammaLUT(255, 0.5), map[int]uint8{0: 0, 128: 64, 255: 255}},
+		{"levels", LevelsLUT(255, 50, 150, 1), map[int]uint8{0: 0, 50: 0, 100: 128, 150: 255, 200: 255}},
+		{"levels with gamma", LevelsLUT(255, 0, 255, 2), map[int]uint8{64: 128}},
+		{"levels collapsed", LevelsLUT(255, 100, 100, 1), map[int]uint8{99: 0, 100: 255}},
+		{"brightness", BrightnessContrastLUT(255, 0.1, 1), map[int]uint8{0: 26, 100: 126, 250: 255}},
+		{"contrast", BrightnessContrastLUT(255, 0, 2), map[int]uint8{0: 0, 100: 73, 127: 127, 200: 255}},
+		{"curve identity", CurveLUT(255, []Point{{0, 0}, {255, 255}}), map[int]uint8{0: 0, 77: 77, 255: 255}},
+		{"no curve points", CurveLUT(255, nil), map[int]uint8{0: 0, 77: 77, 255: 255}},
+		{"single curve point", CurveLUT(255, []Point{{10, 40}}), map[int]uint8{0: 40, 200: 40}},
+		{"flat curve ends", CurveLUT(255, []Point{{200, 240}, {50, 10}}), map[int]uint8{0: 10, 50: 10, 200: 240, 255: 240}},
+		{"curve through points", CurveLUT(255, []Point{{0, 0}, {64, 200}, {255, 255}}), map[int]uint8{0: 0, 64: 200, 255: 255}},
+		{"small max", GammaLUT(3, 1), map[int]uint8{0: 0, 3: 3}},
+	}
+	for _, tt := range tests {
+		for v, want := range tt.want {
+			if got := tt.lut[v]; got != want {
+				t.Errorf("%s: value %d maps to %d, want %d", tt.name, v, got, want)
+			}
+		}
+	}
+}
+
+func TestCurveLUTMonotone(t *testing.T) {
+	// The spline must not overshoot between increasing control points
+	lut := CurveLUT(255, []Point{{0, 0}, {30, 200}, {60, 210}, {255, 255}})
+	for v := 1; v < len(lut); v++ {
+		if lut[v] < lut[v-1] {
+			t.Fatalf("value %d maps to %d, below %d for value %d", v, lut[v], lut[v-1], v-1)
+		}
+	}
+}
+
+func TestApplyLUT(t *testing.T) {
+	pgm := &PGM{data: [][]uint8{{0, 1, 2, 3}}, width: 4, height: 1, magicNumber: "P2", max: 3}
+	pgm.ApplyLUT(LUT{3, 2, 1, 0})
+	if want := []uint8{3, 2, 1, 0}; !reflect.DeepEqual(pgm.data[0], want) {
+		t.Errorf("got %v, want %v", pgm.data[0], want)
+	}
+
+	ppm := &PPM{data: [][]Pixel{{{0, 1, 2}}}, width: 1, height: 1, magicNumber: "P3", max: 3}
+	ppm.ApplyChannelLUTs(LUT{3, 3, 3, 3}, LUT{0, 0, 0, 0}, LUT{0, 1, 2, 3})
+	if want := (Pixel{3, 0, 2}); ppm.data[0][0] != want {
+		t.Errorf("got %v, want %v", ppm.data[0][0], want)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	pgm := &PGM{data: [][]uint8{{50, 100, 150}}, width: 3, height: 1, magicNumber: "P2", max: 255}
+	pgm.Normalize(0, 0)
+	if want := []uint8{0, 128, 255}; !reflect.DeepEqual(pgm.data[0], want) {
+		t.Errorf("got %v, want %v", pgm.data[0], want)
+	}
+}
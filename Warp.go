@@ -0,0 +1,260 @@
+package Netpbm
+
+import (
+	"errors"
+	"math"
+)
+
+// Matrix is a 3x3 projective transform acting on (x, y, 1) column vectors.
+// Affine transforms leave the last row at (0, 0, 1).
+type Matrix [3][3]float64
+
+// IdentityMatrix returns the transform that leaves every point in place.
+func IdentityMatrix() Matrix {
+	return Matrix{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+}
+
+// AffineMatrix turns a 2x3 affine matrix into a Matrix.
+func AffineMatrix(a [2][3]float64) Matrix {
+	return Matrix{a[0], a[1], {0, 0, 1}}
+}
+
+// TranslationMatrix returns the transform that moves every point by (dx, dy).
+func TranslationMatrix(dx, dy float64) Matrix {
+	return Matrix{{1, 0, dx}, {0, 1, dy}, {0, 0, 1}}
+}
+
+// ScaleMatrix returns the transform that scales every point around the origin.
+func ScaleMatrix(sx, sy float64) Matrix {
+	return Matrix{{sx, 0, 0}, {0, sy, 0}, {0, 0, 1}}
+}
+
+// RotationMatrix returns the transform that rotates every point clockwise
+// by angle degrees around the origin.
+func RotationMatrix(angle float64) Matrix {
+	rad := angle * math.Pi / 180
+	cos, sin := math.Cos(rad), math.Sin(rad)
+	return Matrix{{cos, -sin, 0}, {sin, cos, 0}, {0, 0, 1}}
+}
+
+// ShearMatrix returns the transform that shifts x by shx*y and y by shy*x.
+func ShearMatrix(shx, shy float64) Matrix {
+	return Matrix{{1, shx, 0}, {shy, 1, 0}, {0, 0, 1}}
+}
+
+// Multiply returns the transform that applies n first and then m.
+func (m Matrix) Multiply(n Matrix) Matrix {
+	var r Matrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				r[i][j] += m[i][k] * n[k][j]
+			}
+		}
+	}
+	return r
+}
+
+// Apply transforms the point (x, y).
+func (m Matrix) Apply(x, y float64) (float64, float64) {
+	w := m[2][0]*x + m[2][1]*y + m[2][2]
+	return (m[0][0]*x + m[0][1]*y + m[0][2]) / w, (m[1][0]*x + m[1][1]*y + m[1][2]) / w
+}
+
+// Inverse returns the inverse transform, or an error if the matrix is singular.
+func (m Matrix) Inverse() (Matrix, error) {
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	if math.Abs(det) < 1e-12 {
+		return Matrix{}, errors.New("matrix is not invertible")
+	}
+
+	// Adjugate divided by the determinant
+	var inv Matrix
+	inv[0][0] = (m[1][1]*m[2][2] - m[1][2]*m[2][1]) / det
+	inv[0][1] = (m[0][2]*m[2][1] - m[0][1]*m[2][2]) / det
+	inv[0][2] = (m[0][1]*m[1][2] - m[0][2]*m[1][1]) / det
+	inv[1][0] = (m[1][2]*m[2][0] - m[1][0]*m[2][2]) / det
+	inv[1][1] = (m[0][0]*m[2][2] - m[0][2]*m[2][0]) / det
+	inv[1][2] = (m[0][2]*m[1][0] - m[0][0]*m[1][2]) / det
+	inv[2][0] = (m[1][0]*m[2][1] - m[1][1]*m[2][0]) / det
+	inv[2][1] = (m[0][1]*m[2][0] - m[0][0]*m[2][1]) / det
+	inv[2][2] = (m[0][0]*m[1][1] - m[0][1]*m[1][0]) / det
+	return inv, nil
+}
+
+// HomographyFromPoints computes the perspective transform that maps each of
+// the four src points onto the matching dst point.
+func HomographyFromPoints(src, dst [4]Point) (Matrix, error) {
+	// Build the 8x8 linear system for the unknowns h00..h21, with h22 fixed to 1
+	var a [8][9]float64
+	for i := 0; i < 4; i++ {
+		x, y := float64(src[i].X), float64(src[i].Y)
+		u, v := float64(dst[i].X), float64(dst[i].Y)
+		a[2*i] = [9]float64{x, y, 1, 0, 0, 0, -x * u, -y * u, u}
+		a[2*i+1] = [9]float64{0, 0, 0, x, y, 1, -x * v, -y * v, v}
+	}
+
+	// Gaussian elimination with partial pivoting
+	for col := 0; col < 8; col++ {
+		pivot := col
+		for row := col + 1; row < 8; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return Matrix{}, errors.New("points are degenerate, three of them are collinear")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		for row := 0; row < 8; row++ {
+			if row == col {
+				continue
+			}
+			f := a[row][col] / a[col][col]
+			for k := col; k < 9; k++ {
+				a[row][k] -= f * a[col][k]
+			}
+		}
+	}
+
+	var h [8]float64
+	for i := range h {
+		h[i] = a[i][8] / a[i][i]
+	}
+	return Matrix{{h[0], h[1], h[2]}, {h[3], h[4], h[5]}, {h[6], h[7], 1}}, nil
+}
+
+// warpChannel builds a width x height channel where each pixel is read from
+// the source position given by the inverse transform inv.
+func warpChannel(src channel, inv Matrix, width, height int, filter ResizeFilter, edge EdgeMode, outside float64) channel {
+	dst := newChannel(width, height)
+	parallelRows(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < width; x++ {
+				sx, sy := inv.Apply(float64(x), float64(y))
+				if math.IsNaN(sx) || math.IsInf(sx, 0) || math.IsNaN(sy) || math.IsInf(sy, 0) {
+					dst[y][x] = outside
+					continue
+				}
+				dst[y][x] = src.sample(sx, sy, filter, edge, outside)
+			}
+		}
+	})
+	return dst
+}
+
+// Warp applies the transform m, which maps source coordinates to destination
+// coordinates, to the PGM image. The image keeps its size; pixels that map
+// outside of the source are handled by the edge mode, EdgeConstant using background.
+// The filter must be NearestNeighbor, Bilinear or Bicubic.
+func (pgm *PGM) Warp(m Matrix, filter ResizeFilter, edge EdgeMode, background uint8) error {
+	if err := checkSampling(filter); err != nil {
+		return err
+	}
+	inv, err := m.Inverse()
+	if err != nil {
+		return err
+	}
+	pgm.data = warpChannel(pgm.channel(), inv, pgm.width, pgm.height, filter, edge, float64(background)).toUint8(pgm.max)
+	return nil
+}
+
+// Warp applies the transform m, which maps source coordinates to destination
+// coordinates, to the PPM image. The image keeps its size; pixels that map
+// outside of the source are handled by the edge mode, EdgeConstant using background.
+// The filter must be NearestNeighbor, Bilinear or Bicubic.
+func (ppm *PPM) Warp(m Matrix, filter ResizeFilter, edge EdgeMode, background Pixel) error {
+	if err := checkSampling(filter); err != nil {
+		return err
+	}
+	inv, err := m.Inverse()
+	if err != nil {
+		return err
+	}
+	ch := ppm.channels()
+	fill := pixelSamples(background)
+	for i := range ch {
+		ch[i] = warpChannel(ch[i], inv, ppm.width, ppm.height, filter, edge, fill[i])
+	}
+	ppm.data = mergeChannels(ch, ppm.max)
+	return nil
+}
+
+// maxShearGrowth bounds how much Shear may widen the canvas, relative to the
+// largest side of the image.
+const maxShearGrowth = 16
+
+// shearTransform returns the horizontal shear by angle degrees used by Shear,
+// shifted so that the result fits a canvas of the returned width. Like
+// pnmshear, it rejects angles of 90 degrees or more either way, and it also
+// rejects angles so steep that rows would move by more than maxShearGrowth
+// times the size of the image.
+func shearTransform(width, height int, angle float64) (Matrix, int, error) {
+	if math.IsNaN(angle) || math.Abs(angle) >= 90 {
+		return Matrix{}, 0, errors.New("shear angle must be between -90 and 90 degrees")
+	}
+	shift := math.Tan(angle * math.Pi / 180)
+	extra := math.Abs(shift) * float64(height-1)
+	// Angles very close to 90 degrees would need a huge canvas
+	if extra > maxShearGrowth*float64(max(width, height)) {
+		return Matrix{}, 0, errors.New("shear angle is too steep for the image height")
+	}
+	m := ShearMatrix(shift, 0)
+	if shift < 0 {
+		m = TranslationMatrix(extra, 0).Multiply(m)
+	}
+	return m, width + int(math.Ceil(extra-1e-9)), nil
+}
+
+// Shear shears the PGM image horizontally by angle degrees, like pnmshear:
+// each row is shifted right by its distance from the top times tan(angle).
+// The canvas is widened to hold the whole image and the gaps are filled with background.
+// The angle must be strictly between -90 and 90 degrees, and not so close to
+// them that the canvas would grow by more than 16 times the image size. The
+// filter must be NearestNeighbor, Bilinear or Bicubic.
+func (pgm *PGM) Shear(angle float64, filter ResizeFilter, background uint8) error {
+	if err := checkSampling(filter); err != nil {
+		return err
+	}
+	m, width, err := shearTransform(pgm.width, pgm.height, angle)
+	if err != nil {
+		return err
+	}
+	inv, err := m.Inverse()
+	if err != nil {
+		return err
+	}
+	pgm.data = warpChannel(pgm.channel(), inv, width, pgm.height, filter, EdgeConstant, float64(background)).toUint8(pgm.max)
+	pgm.width = width
+	return nil
+}
+
+// Shear shears the PPM image horizontally by angle degrees, like pnmshear:
+// each row is shifted right by its distance from the top times tan(angle).
+// The canvas is widened to hold the whole image and the gaps are filled with background.
+// The angle must be strictly between -90 and 90 degrees, and not so close to
+// them that the canvas would grow by more than 16 times the image size. The
+// filter must be NearestNeighbor, Bilinear or Bicubic.
+func (ppm *PPM) Shear(angle float64, filter ResizeFilter, background Pixel) error {
+	if err := checkSampling(filter); err != nil {
+		return err
+	}
+	m, width, err := shearTransform(ppm.width, ppm.height, angle)
+	if err != nil {
+		return err
+	}
+	inv, err := m.Inverse()
+	if err != nil {
+		return err
+	}
+	ch := ppm.channels()
+	fill := pixelSamples(background)
+	for i := range ch {
+		ch[i] = warpChannel(ch[i], inv, width, ppm.height, filter, EdgeConstant, fill[i])
+	}
+	ppm.data = mergeChannels(ch, ppm.max)
+	ppm.width = width
+	return nil
+}
@@ -0,0 +1,81 @@
+package Netpbm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHomographyFromPoints(t *testing.T) {
+	square := [4]Point{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	tests := []struct {
+		name     string
+		src, dst [4]Point
+	}{
+		{"identity", square, square},
+		{"translation", square, [4]Point{{5, 7}, {15, 7}, {15, 17}, {5, 17}}},
+		{"scale", square, [4]Point{{0, 0}, {30, 0}, {30, 20}, {0, 20}}},
+		{"perspective", square, [4]Point{{2, 1}, {40, 5}, {35, 30}, {0, 25}}},
+		{"origin first on the target", [4]Point{{3, 4}, {50, 2}, {47, 60}, {1, 33}}, square},
+	}
+	for _, tt := range tests {
+		m, err := HomographyFromPoints(tt.src, tt.dst)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		for i := range tt.src {
+			x, y := m.Apply(float64(tt.src[i].X), float64(tt.src[i].Y))
+			if math.Abs(x-float64(tt.dst[i].X)) > 1e-9 || math.Abs(y-float64(tt.dst[i].Y)) > 1e-9 {
+				t.Errorf("%s: %v maps to (%g, %g), want %v", tt.name, tt.src[i], x, y, tt.dst[i])
+			}
+		}
+	}
+}
+
+func TestHomographyFromPointsDegenerate(t *testing.T) {
+	square := [4]Point{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	tests := []struct {
+		name     string
+		src, dst [4]Point
+	}{
+		{"collinear source", [4]Point{{0, 0}, {5, 5}, {10, 10}, {0, 10}}, square},
+		{"repeated source", [4]Point{{0, 0}, {0, 0}, {10, 10}, {0, 10}}, square},
+		{"all on a line", [4]Point{{0, 0}, {1, 0}, {2, 0}, {3, 0}}, square},
+	}
+	for _, tt := range tests {
+		if _, err := HomographyFromPoints(tt.src, tt.dst); err == nil {
+			t.Errorf("%s: got no error", tt.name)
+		}
+	}
+}
+
+func TestShearAngle(t *testing.T) {
+	tests := []struct {
+		angle     float64
+		wantErr   bool
+		wantWidth int
+	}{
+		{0, false, 4},
+		{45, false, 6},
+		{-45, false, 6},
+		{90, true, 0},
+		{-90, true, 0},
+		{135, true, 0},
+		{89.9999999, true, 0},
+		{math.NaN(), true, 0},
+	}
+	for _, tt := range tests {
+		pgm := randomPGM(4, 3, 256, 1)
+		err := pgm.Shear(tt.angle, Bilinear, 0)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Shear(%g): got error %v, want error %v", tt.angle, err, tt.wantErr)
+			continue
+		}
+		if err == nil && pgm.width != tt.wantWidth {
+			t.Errorf("Shear(%g): got width %d, want %d", tt.angle, pgm.width, tt.wantWidth)
+		}
+		if err != nil && (pgm.width != 4 || pgm.height != 3) {
+			t.Errorf("Shear(%g): image resized to %dx%d on error", tt.angle, pgm.width, pgm.height)
+		}
+	}
+}